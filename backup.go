@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+// time format used in the backup file names, sorts chronologically
+const backupTimeFormat = "20060102T150405.000000000Z"
+
+/*
+modifiesDB returns true when the action (might) modify the database.
+*/
+func modifiesDB(call *nu.ExecCommand, action string) bool {
+	switch action {
+	case "set", "add", "delete", "tx", "move", "rename", "clear":
+		return true
+	case "sequence":
+		_, set := call.FlagValue("set")
+		_, next := call.FlagValue("next")
+		return set || next
+	default:
+		return false
+	}
+}
+
+/*
+backup writes consistent snapshot of the database into the backup directory
+and removes the oldest backups of the database so that only "backupKeep"
+latest backups remain. Does nothing when the backup directory is not
+configured.
+*/
+func (cfg *configuration) backup(db *bbolt.DB) error {
+	if cfg.backupDir == "" || cfg.readOnly {
+		return nil
+	}
+	if err := os.MkdirAll(cfg.backupDir, 0700); err != nil {
+		return fmt.Errorf("creating backup directory: %w", err)
+	}
+
+	base, err := backupPrefix(db.Path())
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(cfg.backupDir, base+"."+time.Now().UTC().Format(backupTimeFormat)+".bak")
+	err = db.View(func(tx *bbolt.Tx) error {
+		// write into temporary file so that incomplete backup is never left behind
+		f, err := os.CreateTemp(cfg.backupDir, base+".*.tmp")
+		if err != nil {
+			return err
+		}
+		if _, err := tx.WriteTo(f); err != nil {
+			return errors.Join(err, f.Close(), os.Remove(f.Name()))
+		}
+		// make sure the content is on disk before the file gets it's final name
+		if err := f.Sync(); err != nil {
+			return errors.Join(err, f.Close(), os.Remove(f.Name()))
+		}
+		if err := f.Close(); err != nil {
+			return errors.Join(err, os.Remove(f.Name()))
+		}
+		return os.Rename(f.Name(), name)
+	})
+	if err != nil {
+		return fmt.Errorf("writing backup: %w", err)
+	}
+
+	if cfg.backupKeep <= 0 {
+		return nil
+	}
+	backups, err := listBackups(cfg.backupDir, base)
+	if err != nil {
+		return err
+	}
+	for len(backups) > int(cfg.backupKeep) {
+		if err := os.Remove(filepath.Join(cfg.backupDir, backups[0])); err != nil {
+			return fmt.Errorf("removing old backup: %w", err)
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+/*
+backupPrefix returns the prefix of the backup file names of the database.
+Databases with the same file name might be in different directories so the
+prefix contains hash of the absolute path of the database in addition to
+the file name, ie "data.db.1a2b3c4d".
+*/
+func backupPrefix(dbPath string) (string, error) {
+	abs, err := filepath.Abs(dbPath)
+	if err != nil {
+		return "", fmt.Errorf("resolving absolute path of the database: %w", err)
+	}
+	h := sha256.Sum256([]byte(abs))
+	return filepath.Base(abs) + "." + hex.EncodeToString(h[:4]), nil
+}
+
+/*
+listBackups returns names of the backup files of the database (base is the
+prefix returned by backupPrefix), oldest first.
+*/
+func listBackups(dir, base string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading backup directory: %w", err)
+	}
+	var backups []string
+	for _, f := range files {
+		name := f.Name()
+		if f.Type().IsRegular() && strings.HasPrefix(name, base+".") && strings.HasSuffix(name, ".bak") && len(name) == len(base)+len(backupTimeFormat)+5 {
+			backups = append(backups, name)
+		}
+	}
+	slices.Sort(backups)
+	return backups, nil
+}
+
+/*
+restore replaces the database file with the backup (by default the latest
+backup of the database). Current state of the database is backed up first
+so the restore can be undone.
+*/
+func restore(ctx context.Context, call *nu.ExecCommand) error {
+	cfg, err := loadCfg(ctx, call)
+	if err != nil {
+		return err
+	}
+	if cfg.backupDir == "" {
+		return nu.Error{
+			Err:  errors.New("backup directory is not configured"),
+			Code: "boltdb::config::backupDir",
+			Url:  "https://github.com/ainvaltin/nu_plugin_boltdb?tab=readme-ov-file#configuration",
+			Help: `Set the "backupDir" configuration option to enable backups.`,
+		}
+	}
+	if cfg.readOnly {
+		return errors.New(`database can't be restored as the "ReadOnly" configuration flag is set to "true"`)
+	}
+
+	dbName := call.Positional[0].Value.(string)
+	var name string
+	if v, ok := call.FlagValue("backup"); ok {
+		if name = v.Value.(string); !filepath.IsAbs(name) {
+			name = filepath.Join(cfg.backupDir, name)
+		}
+		if _, err := os.Stat(name); err != nil {
+			return (&nu.Error{Err: fmt.Errorf("invalid backup file: %w", err)}).AddLabel("backup not found", v.Span)
+		}
+	} else {
+		if name, err = latestBackup(cfg.backupDir, dbName); err != nil {
+			return err
+		}
+		if name == "" {
+			return (&nu.Error{Err: errors.New("database has no backups")}).AddLabel("no backups found", call.Positional[0].Span)
+		}
+	}
+
+	if err := cfg.restoreBackup(dbName, name, boolFlag(call, "force")); err != nil {
+		return err
+	}
+	return call.ReturnValue(ctx, nu.Value{Value: name})
+}
+
+/*
+latestBackup returns the path of the latest backup of the database in the
+backup directory dir, empty string when the database has no backups.
+*/
+func latestBackup(dir, dbName string) (string, error) {
+	base, err := backupPrefix(dbName)
+	if err != nil {
+		return "", err
+	}
+	backups, err := listBackups(dir, base)
+	if err != nil || len(backups) == 0 {
+		return "", err
+	}
+	return filepath.Join(dir, backups[len(backups)-1]), nil
+}
+
+/*
+restoreBackup replaces the database file dbName with the backup file name.
+Unless force is true the current database is opened (and thus locked) for
+the duration of the swap and backed up first - when the current database is
+corrupt and can't be opened force allows to replace it anyway.
+*/
+func (cfg *configuration) restoreBackup(dbName, name string, force bool) error {
+	// copy the backup next to the database and check it's a valid database
+	// before replacing the database with it
+	tmp, err := copyFile(name, filepath.Dir(dbName), cfg.fileMode)
+	if err != nil {
+		return fmt.Errorf("copying backup: %w", err)
+	}
+	defer os.Remove(tmp)
+	bdb, err := bbolt.Open(tmp, cfg.fileMode, &bbolt.Options{Timeout: cfg.timeout, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("invalid backup file %s: %w", name, err)
+	}
+	if err := bdb.Close(); err != nil {
+		return err
+	}
+
+	// the database is kept open (ie locked) until it has been replaced so
+	// that it's not in use by other process during the swap
+	if _, err := os.Stat(dbName); err == nil && !force {
+		db, err := bbolt.Open(dbName, cfg.fileMode, &bbolt.Options{Timeout: cfg.timeout})
+		if err != nil {
+			return nu.Error{
+				Err:  fmt.Errorf("opening bolt db: %w", err),
+				Help: `Use flag "force" to replace the database which can't be opened (the current database is then not backed up).`,
+			}
+		}
+		if err := cfg.backup(db); err != nil {
+			return errors.Join(err, db.Close())
+		}
+		if err := os.Rename(tmp, dbName); err != nil {
+			return errors.Join(fmt.Errorf("replacing database with the backup: %w", err), db.Close())
+		}
+		return db.Close()
+	}
+	if err := os.Rename(tmp, dbName); err != nil {
+		return fmt.Errorf("replacing database with the backup: %w", err)
+	}
+	return nil
+}
+
+/*
+copyFile copies the file into temporary file in the directory dir, returns
+the name of the temporary file.
+*/
+func copyFile(name, dir string, mode os.FileMode) (_ string, err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp(dir, filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(dst.Name())
+		}
+	}()
+	if err := dst.Chmod(mode); err != nil {
+		return "", errors.Join(err, dst.Close())
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		return "", errors.Join(err, dst.Close())
+	}
+	if err := dst.Sync(); err != nil {
+		return "", errors.Join(err, dst.Close())
+	}
+	return dst.Name(), dst.Close()
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+func Test_backup(t *testing.T) {
+	db := testDB(t, "a")
+	cfg := configuration{fileMode: 0600, backupDir: filepath.Join(t.TempDir(), "backups"), backupKeep: 2}
+
+	for range 3 {
+		if err := cfg.backup(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	base, err := backupPrefix(db.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	backups, err := listBackups(cfg.backupDir, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups to be kept, got %q", backups)
+	}
+
+	bdb, err := bbolt.Open(filepath.Join(cfg.backupDir, backups[1]), 0600, &bbolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bdb.Close()
+	err = bdb.View(func(tx *bbolt.Tx) error {
+		if v := tx.Bucket([]byte("test")).Get([]byte("a")); string(v) != "value of a" {
+			t.Errorf("unexpected value in the backup: %q", v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_backup_sameFileName(t *testing.T) {
+	// databases with the same file name in different directories
+	dbs := make([]*bbolt.DB, 2)
+	for i := range dbs {
+		db, err := bbolt.Open(filepath.Join(t.TempDir(), "data.db"), 0600, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		dbs[i] = db
+	}
+	cfg := configuration{fileMode: 0600, backupDir: filepath.Join(t.TempDir(), "backups"), backupKeep: 1}
+
+	for _, db := range dbs {
+		if err := cfg.backup(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var names []string
+	for _, db := range dbs {
+		base, err := backupPrefix(db.Path())
+		if err != nil {
+			t.Fatal(err)
+		}
+		backups, err := listBackups(cfg.backupDir, base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// rotation of one database must not remove the backups of the other
+		if len(backups) != 1 {
+			t.Fatalf("expected 1 backup of %s, got %q", db.Path(), backups)
+		}
+		names = append(names, backups[0])
+	}
+	if names[0] == names[1] {
+		t.Errorf("expected databases to have different backups, got %q", names)
+	}
+}
+
+func Test_restoreBackup(t *testing.T) {
+	// writeDB sets the value of the key "k" in the bucket "test"
+	writeDB := func(t *testing.T, name, value string) {
+		t.Helper()
+		db, err := bbolt.Open(name, 0600, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		err = db.Update(func(tx *bbolt.Tx) error {
+			b, err := tx.CreateBucketIfNotExists([]byte("test"))
+			if err != nil {
+				return err
+			}
+			return b.Put([]byte("k"), []byte(value))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	readDB := func(t *testing.T, name string) (value string) {
+		t.Helper()
+		db, err := bbolt.Open(name, 0600, &bbolt.Options{ReadOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		err = db.View(func(tx *bbolt.Tx) error {
+			value = string(tx.Bucket([]byte("test")).Get([]byte("k")))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+	backup := func(t *testing.T, cfg *configuration, name string) {
+		t.Helper()
+		db, err := bbolt.Open(name, 0600, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if err := cfg.backup(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// setup creates database with backups of the values "v1" and "v2", the
+	// current value is "v3"
+	setup := func(t *testing.T) (cfg *configuration, dbName string) {
+		t.Helper()
+		cfg = &configuration{fileMode: 0600, backupDir: filepath.Join(t.TempDir(), "backups")}
+		dbName = filepath.Join(t.TempDir(), "data.db")
+		for _, v := range []string{"v1", "v2"} {
+			writeDB(t, dbName, v)
+			backup(t, cfg, dbName)
+		}
+		writeDB(t, dbName, "v3")
+		return cfg, dbName
+	}
+	backups := func(t *testing.T, cfg *configuration, dbName string) []string {
+		t.Helper()
+		base, err := backupPrefix(dbName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		backups, err := listBackups(cfg.backupDir, base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return backups
+	}
+
+	t.Run("latest", func(t *testing.T) {
+		cfg, dbName := setup(t)
+		name, err := latestBackup(cfg.backupDir, dbName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cfg.restoreBackup(dbName, name, false); err != nil {
+			t.Fatal(err)
+		}
+		if v := readDB(t, dbName); v != "v2" {
+			t.Errorf("expected value of the latest backup, got %q", v)
+		}
+		// the state before the restore is backed up
+		b := backups(t, cfg, dbName)
+		if len(b) != 3 {
+			t.Fatalf("expected 3 backups, got %q", b)
+		}
+		if v := readDB(t, filepath.Join(cfg.backupDir, b[2])); v != "v3" {
+			t.Errorf("expected the state before restore to be backed up, got %q", v)
+		}
+	})
+
+	t.Run("explicit backup", func(t *testing.T) {
+		cfg, dbName := setup(t)
+		if err := cfg.restoreBackup(dbName, filepath.Join(cfg.backupDir, backups(t, cfg, dbName)[0]), false); err != nil {
+			t.Fatal(err)
+		}
+		if v := readDB(t, dbName); v != "v1" {
+			t.Errorf("expected value of the first backup, got %q", v)
+		}
+	})
+
+	t.Run("invalid backup", func(t *testing.T) {
+		cfg, dbName := setup(t)
+		name := filepath.Join(cfg.backupDir, "garbage.bak")
+		if err := os.WriteFile(name, bytes.Repeat([]byte("x"), 8192), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := cfg.restoreBackup(dbName, name, false); err == nil || !strings.Contains(err.Error(), "invalid backup file") {
+			t.Errorf("expected invalid backup error, got %v", err)
+		}
+		if v := readDB(t, dbName); v != "v3" {
+			t.Errorf("expected database not to change, got %q", v)
+		}
+		// temporary copy of the backup is removed
+		if files, _ := filepath.Glob(filepath.Join(filepath.Dir(dbName), "*.tmp")); len(files) != 0 {
+			t.Errorf("temporary files left behind: %q", files)
+		}
+	})
+
+	t.Run("missing database", func(t *testing.T) {
+		cfg, dbName := setup(t)
+		name, err := latestBackup(cfg.backupDir, dbName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Remove(dbName); err != nil {
+			t.Fatal(err)
+		}
+		if err := cfg.restoreBackup(dbName, name, false); err != nil {
+			t.Fatal(err)
+		}
+		if v := readDB(t, dbName); v != "v2" {
+			t.Errorf("expected value of the latest backup, got %q", v)
+		}
+	})
+
+	t.Run("corrupt database", func(t *testing.T) {
+		cfg, dbName := setup(t)
+		name, err := latestBackup(cfg.backupDir, dbName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dbName, bytes.Repeat([]byte("x"), 8192), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := cfg.restoreBackup(dbName, name, false); err == nil {
+			t.Fatal("expected error restoring over database which can't be opened")
+		}
+		if err := cfg.restoreBackup(dbName, name, true); err != nil {
+			t.Fatal(err)
+		}
+		if v := readDB(t, dbName); v != "v2" {
+			t.Errorf("expected value of the latest backup, got %q", v)
+		}
+	})
+
+	t.Run("no backups", func(t *testing.T) {
+		cfg := &configuration{fileMode: 0600, backupDir: t.TempDir()}
+		if name, err := latestBackup(cfg.backupDir, filepath.Join(t.TempDir(), "data.db")); err != nil || name != "" {
+			t.Errorf("expected no backup, got %q, %v", name, err)
+		}
+	})
+}
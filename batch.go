@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+/*
+setValues is the batch mode of the "set" action - input is list of
+{key, value} or {bucket, key, value} records.
+
+Bulk-load flags: "fill-percent" sets the fill percent of the buckets written,
+"presort" sorts the records by bucket and key before writing and "no-sync"
+disables fsync for the duration of the load (database is synced at the end).
+*/
+func setValues(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) (err error) {
+	path, _, err := location(call)
+	if err != nil {
+		return err
+	}
+
+	var every int64
+	if v, ok := call.FlagValue("commit-every"); ok {
+		if every, err = nonNegative(v); err != nil {
+			return err
+		}
+	}
+	if isDryRun(call) {
+		// changes are rolled back so the summary must cover all the records
+		every = 0
+	}
+
+	fill, err := getFillPercent(call)
+	if err != nil {
+		return err
+	}
+
+	next, err := inputRecords(call)
+	if err != nil {
+		return err
+	}
+	encodeKey := getKeyEncoder(call)
+	if boolFlag(call, "presort") {
+		if next, err = sortRecords(next, path, encodeKey); err != nil {
+			return err
+		}
+	}
+	if boolFlag(call, "no-sync") && !isDryRun(call) {
+		db.NoSync = true
+		defer func() {
+			db.NoSync = false
+			if e := db.Sync(); e != nil {
+				err = errors.Join(err, fmt.Errorf("syncing database: %w", e))
+			}
+		}()
+	}
+	create := boolFlag(call, "create")
+	bucket := bucketFunc(create)
+
+	for done := false; !done; {
+		sent, err := update(ctx, db, call, func(tx *writeTx) error {
+			for cnt := int64(0); every == 0 || cnt < every; cnt++ {
+				v, ok := next()
+				if !ok {
+					done = true
+					return nil
+				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+
+				item, err := toKVRecord(v, path, encodeKey)
+				if err != nil {
+					return err
+				}
+				b, err := bucket(tx, item.bucket)
+				if err != nil {
+					return err
+				}
+				if fill != 0 {
+					b.FillPercent = fill
+				}
+				if err := tx.put(b, item.key, item.value); err != nil {
+					return (&nu.Error{Err: fmt.Errorf("writing key: %w", err)}).AddLabel("failed to write the record", v.Span)
+				}
+			}
+			return nil
+		})
+		if sent || err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+inputRecords returns func which returns next item of the input list. The
+bool return value is false when there is no more items.
+*/
+func inputRecords(call *nu.ExecCommand) (func() (nu.Value, bool), error) {
+	switch in := call.Input.(type) {
+	case nu.Value:
+		items, ok := in.Value.([]nu.Value)
+		if !ok {
+			return nil, (&nu.Error{Err: fmt.Errorf("expected list of records as input, got %T", in.Value)}).AddLabel("expected list", in.Span)
+		}
+		return func() (v nu.Value, ok bool) {
+			if len(items) == 0 {
+				return v, false
+			}
+			v, items = items[0], items[1:]
+			return v, true
+		}, nil
+	case <-chan nu.Value:
+		return func() (nu.Value, bool) {
+			v, ok := <-in
+			return v, ok
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported input type %T, expected list of records", call.Input)
+	}
+}
+
+/*
+sortRecords reads all the records returned by next and returns func which
+returns them sorted by bucket path and key. The sort is stable so when the
+same key is given multiple times the last value is still written last.
+*/
+func sortRecords(next func() (nu.Value, bool), defBucket []boltItem, encodeKey func(nu.Value) ([]byte, error)) (func() (nu.Value, bool), error) {
+	type item struct {
+		kvRecord
+		v nu.Value
+	}
+	var items []item
+	for v, ok := next(); ok; v, ok = next() {
+		r, err := toKVRecord(v, defBucket, encodeKey)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item{kvRecord: r, v: v})
+	}
+
+	slices.SortStableFunc(items, func(a, b item) int {
+		if c := slices.CompareFunc(a.bucket, b.bucket, func(a, b boltItem) int { return bytes.Compare(a.name, b.name) }); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.key, b.key)
+	})
+
+	return func() (v nu.Value, ok bool) {
+		if len(items) == 0 {
+			return v, false
+		}
+		v, items = items[0].v, items[1:]
+		return v, true
+	}, nil
+}
+
+/*
+kvRecord is a key/value pair to be written into the database.
+*/
+type kvRecord struct {
+	bucket []boltItem
+	key    []byte
+	value  []byte
+}
+
+/*
+toKVRecord converts {bucket, key, value} record to kvRecord, when the record
+doesn't have "bucket" field the defBucket is used. The key is converted to
+bytes by encodeKey (see getKeyEncoder).
+*/
+func toKVRecord(v nu.Value, defBucket []boltItem, encodeKey func(nu.Value) ([]byte, error)) (r kvRecord, err error) {
+	rec, ok := v.Value.(nu.Record)
+	if !ok {
+		return r, (&nu.Error{Err: fmt.Errorf("expected record, got %T", v.Value)}).AddLabel("expected {key, value} record", v.Span)
+	}
+
+	if r.bucket, err = recordBucket(v, defBucket); err != nil {
+		return r, err
+	}
+
+	k, ok := rec["key"]
+	if !ok {
+		return r, (&nu.Error{Err: errors.New(`record doesn't have "key" field`)}).AddLabel("key missing", v.Span)
+	}
+	if r.key, err = encodeKey(k); err != nil {
+		return r, fmt.Errorf("invalid key name: %w", err)
+	}
+
+	value, ok := rec["value"]
+	if !ok {
+		return r, (&nu.Error{Err: errors.New(`record doesn't have "value" field`)}).AddLabel("value missing", v.Span)
+	}
+	if r.value, err = toBytes(value); err != nil {
+		return r, fmt.Errorf("invalid value: %w", err)
+	}
+	return r, nil
+}
+
+/*
+recordBucket returns the bucket path from the "bucket" field of the record,
+when the record doesn't have "bucket" field the defBucket is used.
+*/
+func recordBucket(v nu.Value, defBucket []boltItem) (path []boltItem, err error) {
+	path = defBucket
+	if b, ok := v.Value.(nu.Record)["bucket"]; ok {
+		if path, err = toPath(b); err != nil {
+			return nil, fmt.Errorf("invalid bucket name: %w", err)
+		}
+	}
+	if len(path) == 0 {
+		return nil, (&nu.Error{Err: errors.New("bucket is not defined")}).AddLabel(`record must have "bucket" field when "bucket" flag is not given`, v.Span)
+	}
+	return path, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+func Test_sortRecords(t *testing.T) {
+	rec := func(bucket, key, value string) nu.Value {
+		r := nu.Record{"key": nu.Value{Value: key}, "value": nu.Value{Value: value}}
+		if bucket != "" {
+			r["bucket"] = nu.Value{Value: bucket}
+		}
+		return nu.Value{Value: r}
+	}
+	input := []nu.Value{
+		rec("b", "2", "first"),
+		rec("", "9", "default bucket"),
+		rec("b", "1", ""),
+		rec("b", "2", "second"),
+		rec("c", "0", ""),
+	}
+
+	next, err := sortRecords(func() (v nu.Value, ok bool) {
+		if len(input) == 0 {
+			return v, false
+		}
+		v, input = input[0], input[1:]
+		return v, true
+	}, []boltItem{{name: []byte("a")}}, toBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"a/9/default bucket", "b/1/", "b/2/first", "b/2/second", "c/0/"}
+	var got []string
+	for v, ok := next(); ok; v, ok = next() {
+		r, err := toKVRecord(v, []boltItem{{name: []byte("a")}}, toBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(r.bucket[0].name)+"/"+string(r.key)+"/"+string(r.value))
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func Test_toKVRecord_keyCodec(t *testing.T) {
+	for _, n := range []int64{42, 300} {
+		v := nu.Value{Value: nu.Record{"key": nu.Value{Value: n}, "value": nu.Value{Value: []byte{1}}}}
+		r, err := toKVRecord(v, []boltItem{{name: []byte("a")}}, keyEncoder("u64be"))
+		if err != nil {
+			t.Errorf("key %d: %v", n, err)
+			continue
+		}
+		if exp := binary.BigEndian.AppendUint64(nil, uint64(n)); !slices.Equal(r.key, exp) {
+			t.Errorf("key %d: expected %x, got %x", n, exp, r.key)
+		}
+	}
+}
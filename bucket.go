@@ -14,16 +14,41 @@ func addBucket(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
 		return err
 	}
 
-	return db.Update(func(tx *bbolt.Tx) error {
-		b := tx.Cursor().Bucket()
-		for _, v := range path {
-			if b, err = b.CreateBucketIfNotExists(v.name); err != nil {
-				return nu.Error{
-					Err:    err,
-					Labels: []nu.Label{{Text: "invalid bucket", Span: v.span}},
-				}
+	_, err = update(ctx, db, call, func(tx *writeTx) error {
+		_, err := tx.createBucket(path)
+		return err
+	})
+	return err
+}
+
+/*
+createBucket creates all the buckets in the path which do not exist yet and
+returns the last bucket of the path.
+*/
+func (tx *writeTx) createBucket(path []boltItem) (b *bbolt.Bucket, err error) {
+	b = tx.Cursor().Bucket()
+	for _, v := range path {
+		if v.match != nil {
+			return nil, errWildcard(v)
+		}
+		if b, err = tx.createBucketIn(b, v.name, true); err != nil {
+			return nil, nu.Error{
+				Err:    err,
+				Labels: []nu.Label{{Text: "invalid bucket", Span: v.span}},
 			}
 		}
-		return nil
-	})
+	}
+	return b, nil
+}
+
+/*
+bucketFunc returns function to look up the bucket - when create is true
+the missing buckets of the path are created, otherwise the bucket must
+exist.
+*/
+func bucketFunc(create bool) func(tx *writeTx, path []boltItem) (*bbolt.Bucket, error) {
+	if create {
+		return (*writeTx).createBucket
+	}
+	return (*writeTx).goToBucket
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"slices"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+/*
+truncate (action "clear") removes all the keys (and with the "recursive" flag also nested buckets)
+of the bucket, the bucket itself is not deleted and it's sequence is kept
+unless the "reset-sequence" flag is given.
+*/
+func truncate(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
+	path, _, err := location(call)
+	if err != nil {
+		return err
+	}
+	nested := boolFlag(call, "recursive")
+	resetSeq := boolFlag(call, "reset-sequence")
+
+	var cnt counts
+	sent, err := update(ctx, db, call, func(tx *writeTx) error {
+		b, err := tx.goToBucket(path)
+		if err != nil {
+			return err
+		}
+		if cnt, err = clearBucket(tx, b, nested); err != nil {
+			return err
+		}
+		if resetSeq {
+			return tx.setSequence(b, 0)
+		}
+		return nil
+	})
+	if sent || err != nil {
+		return err
+	}
+	return call.ReturnValue(ctx, cnt.toValue())
+}
+
+/*
+clearBucket deletes all the keys of the bucket, when nested is true the
+nested buckets are deleted too. Returns the counts of the removed items
+(including the content of the removed nested buckets).
+*/
+func clearBucket(tx *writeTx, b *bbolt.Bucket, nested bool) (cnt counts, err error) {
+	match := func(k, v []byte) bool { return v != nil || nested }
+	if err := cnt.add(b, keyRange{}, match, nested); err != nil {
+		return cnt, err
+	}
+
+	// deleting while iterating with cursor might skip items so collect the names first
+	var keys, buckets [][]byte
+	_, err = keyRange{}.forEach(b.Cursor(), match, func(k, v []byte) error {
+		if v != nil {
+			keys = append(keys, slices.Clone(k))
+		} else {
+			buckets = append(buckets, slices.Clone(k))
+		}
+		return nil
+	})
+	if err != nil {
+		return cnt, err
+	}
+
+	for _, k := range keys {
+		if err := tx.deleteKey(b, k); err != nil {
+			return cnt, err
+		}
+	}
+	for _, k := range buckets {
+		if err := tx.deleteBucket(b, k); err != nil {
+			return cnt, err
+		}
+	}
+	return cnt, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+func Test_clearBucket(t *testing.T) {
+	db := testDB(t, "a", "b", "c")
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("test"))
+		nb, err := b.CreateBucket([]byte("nested"))
+		if err != nil {
+			return err
+		}
+		if err := nb.Put([]byte("k"), []byte("v")); err != nil {
+			return err
+		}
+
+		cnt, err := clearBucket(&writeTx{Tx: tx}, b, false)
+		if err != nil {
+			return err
+		}
+		if cnt.keys != 3 || cnt.buckets != 0 {
+			t.Errorf("expected 3 keys and 0 buckets to be removed, got %+v", cnt)
+		}
+		if keys := collectBucketKeys(b); !slices.Equal(keys, []string{"nested"}) {
+			t.Errorf("unexpected items after clear: %q", keys)
+		}
+
+		if cnt, err = clearBucket(&writeTx{Tx: tx}, b, true); err != nil {
+			return err
+		}
+		if cnt.keys != 1 || cnt.buckets != 1 {
+			t.Errorf("expected 1 key and 1 bucket to be removed, got %+v", cnt)
+		}
+		if keys := collectBucketKeys(b); len(keys) != 0 {
+			t.Errorf("unexpected items after recursive clear: %q", keys)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
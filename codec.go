@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+/*
+keyCodec converts typed key to the byte representation used by many
+applications (ie big-endian integers from Bucket.NextSequence) and back.
+*/
+type keyCodec struct {
+	encode func(v nu.Value) ([]byte, error)
+	// decode returns false when the key is not valid for the codec (ie has
+	// wrong length), then the key should be returned as binary.
+	decode func(b []byte) (nu.Value, bool)
+}
+
+var keyCodecs = map[string]keyCodec{
+	"u64be": {
+		encode: func(v nu.Value) ([]byte, error) {
+			n, err := codecInt(v)
+			if err != nil {
+				return nil, err
+			}
+			if n < 0 {
+				return nil, errors.New("expected non-negative integer")
+			}
+			return binary.BigEndian.AppendUint64(nil, uint64(n)), nil
+		},
+		decode: func(b []byte) (nu.Value, bool) {
+			if len(b) != 8 || binary.BigEndian.Uint64(b) > math.MaxInt64 {
+				return nu.Value{}, false
+			}
+			return nu.Value{Value: int64(binary.BigEndian.Uint64(b))}, true
+		},
+	},
+	"i64be": {
+		encode: func(v nu.Value) ([]byte, error) {
+			n, err := codecInt(v)
+			if err != nil {
+				return nil, err
+			}
+			return binary.BigEndian.AppendUint64(nil, uint64(n)), nil
+		},
+		decode: func(b []byte) (nu.Value, bool) {
+			if len(b) != 8 {
+				return nu.Value{}, false
+			}
+			return nu.Value{Value: int64(binary.BigEndian.Uint64(b))}, true
+		},
+	},
+	"unixnano": {
+		encode: func(v nu.Value) ([]byte, error) {
+			if t, ok := v.Value.(time.Time); ok {
+				return binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano())), nil
+			}
+			if s, ok := v.Value.(string); ok {
+				if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+					return binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano())), nil
+				}
+				if t, err := time.Parse(time.DateOnly, s); err == nil {
+					return binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano())), nil
+				}
+			}
+			n, err := codecInt(v)
+			if err != nil {
+				return nil, errors.New("expected date or integer (nanoseconds since Unix epoch)")
+			}
+			return binary.BigEndian.AppendUint64(nil, uint64(n)), nil
+		},
+		decode: func(b []byte) (nu.Value, bool) {
+			if len(b) != 8 {
+				return nu.Value{}, false
+			}
+			return nu.Value{Value: time.Unix(0, int64(binary.BigEndian.Uint64(b))).UTC()}, true
+		},
+	},
+	"uuid": {
+		encode: func(v nu.Value) ([]byte, error) {
+			s, ok := v.Value.(string)
+			if !ok {
+				return nil, errors.New("expected string")
+			}
+			b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+			if err != nil || len(b) != 16 {
+				return nil, fmt.Errorf("invalid UUID %q", s)
+			}
+			return b, nil
+		},
+		decode: func(b []byte) (nu.Value, bool) {
+			if len(b) != 16 {
+				return nu.Value{}, false
+			}
+			return nu.Value{Value: fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])}, true
+		},
+	},
+	"ulid": {
+		encode: func(v nu.Value) ([]byte, error) {
+			s, ok := v.Value.(string)
+			if !ok {
+				return nil, errors.New("expected string")
+			}
+			return parseULID(s)
+		},
+		decode: func(b []byte) (nu.Value, bool) {
+			if len(b) != 16 {
+				return nu.Value{}, false
+			}
+			return nu.Value{Value: formatULID(b)}, true
+		},
+	},
+}
+
+/*
+codecInt returns integer value of v, integer literals given to flags are parsed
+by Nu as strings so decimal string is accepted too.
+*/
+func codecInt(v nu.Value) (int64, error) {
+	switch t := v.Value.(type) {
+	case int64:
+		return t, nil
+	case string:
+		n, err := strconv.ParseInt(t, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("expected integer, got %q", t)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("expected integer, got %T", v.Value)
+	}
+}
+
+/*
+getKeyEncoder returns func to convert key flag value to bytes - when the
+"key-codec" flag is given the codec is used (Binary values are used as is and
+items of the List are concatenated), otherwise toBytes.
+*/
+func getKeyEncoder(call *nu.ExecCommand) func(v nu.Value) ([]byte, error) {
+	cv, ok := call.FlagValue("key-codec")
+	if !ok {
+		return toBytes
+	}
+	return keyEncoder(cv.Value.(string))
+}
+
+func keyEncoder(name string) func(v nu.Value) ([]byte, error) {
+	codec := keyCodecs[name]
+	var encode func(v nu.Value) ([]byte, error)
+	encode = func(v nu.Value) ([]byte, error) {
+		switch t := v.Value.(type) {
+		case []byte:
+			return t, nil
+		case []nu.Value:
+			var r []byte
+			for _, v := range t {
+				b, err := encode(v)
+				if err != nil {
+					return nil, err
+				}
+				r = append(r, b...)
+			}
+			return r, nil
+		}
+		b, err := codec.encode(v)
+		if err != nil {
+			return nil, nu.Error{
+				Err:    fmt.Errorf("encoding key with %q codec: %w", name, err),
+				Labels: []nu.Label{{Text: "invalid " + name + " key", Span: v.Span}},
+			}
+		}
+		return b, nil
+	}
+	return encode
+}
+
+// Crockford's base32 alphabet used by ULID
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+/*
+formatULID encodes 16 byte ULID as 26 character string. The 128 bits are
+encoded as 130 bits (two leading zero bits), five bits per character.
+*/
+func formatULID(b []byte) string {
+	bit := func(i int) byte {
+		if i < 0 {
+			return 0
+		}
+		return (b[i/8] >> (7 - i%8)) & 1
+	}
+
+	var s [26]byte
+	for i := range s {
+		var c byte
+		for j := i*5 - 2; j < i*5+3; j++ {
+			c = c<<1 | bit(j)
+		}
+		s[i] = ulidAlphabet[c]
+	}
+	return string(s[:])
+}
+
+func parseULID(s string) ([]byte, error) {
+	if len(s) != 26 {
+		return nil, fmt.Errorf("invalid ULID %q: must be 26 characters long", s)
+	}
+	b := make([]byte, 16)
+	for i, r := range strings.ToUpper(s) {
+		c := strings.IndexRune(ulidAlphabet, r)
+		if c < 0 || (i == 0 && c > 7) {
+			return nil, fmt.Errorf("invalid ULID %q", s)
+		}
+		for j := range 5 {
+			if n := i*5 - 2 + j; n >= 0 && c&(1<<(4-j)) != 0 {
+				b[n/8] |= 1 << (7 - n%8)
+			}
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+func Test_keyCodecs(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		ts := time.Date(2024, 6, 1, 12, 30, 0, 42, time.UTC)
+		var testCases = []struct {
+			codec string
+			in    nu.Value
+			bin   []byte
+			out   any
+		}{
+			{codec: "u64be", in: nu.Value{Value: int64(42)}, bin: []byte{0, 0, 0, 0, 0, 0, 0, 42}, out: int64(42)},
+			{codec: "u64be", in: nu.Value{Value: "258"}, bin: []byte{0, 0, 0, 0, 0, 0, 1, 2}, out: int64(258)},
+			{codec: "i64be", in: nu.Value{Value: int64(-1)}, bin: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, out: int64(-1)},
+			{codec: "unixnano", in: nu.Value{Value: ts}, bin: []byte{0x17, 0xd4, 0xe1, 0x3a, 0xa9, 0x50, 0xd0, 0x2a}, out: ts},
+			{codec: "unixnano", in: nu.Value{Value: "2024-06-01T12:30:00.000000042Z"}, bin: []byte{0x17, 0xd4, 0xe1, 0x3a, 0xa9, 0x50, 0xd0, 0x2a}, out: ts},
+			{codec: "unixnano", in: nu.Value{Value: ts.UnixNano()}, bin: []byte{0x17, 0xd4, 0xe1, 0x3a, 0xa9, 0x50, 0xd0, 0x2a}, out: ts},
+			{codec: "uuid", in: nu.Value{Value: "0193a5b8-7c3e-7d2a-9f10-0123456789ab"}, bin: []byte{0x01, 0x93, 0xa5, 0xb8, 0x7c, 0x3e, 0x7d, 0x2a, 0x9f, 0x10, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab}, out: "0193a5b8-7c3e-7d2a-9f10-0123456789ab"},
+			{codec: "ulid", in: nu.Value{Value: "01ARZ3NDEKTSV4RRFFQ69G5FAV"}, bin: []byte{0x01, 0x56, 0x3e, 0x3a, 0xb5, 0xd3, 0xd6, 0x76, 0x4c, 0x61, 0xef, 0xb9, 0x93, 0x02, 0xbd, 0x5b}, out: "01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+			{codec: "ulid", in: nu.Value{Value: "7ZZZZZZZZZZZZZZZZZZZZZZZZZ"}, bin: bytes.Repeat([]byte{0xff}, 16), out: "7ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+		}
+
+		for i, tc := range testCases {
+			b, err := keyEncoder(tc.codec)(tc.in)
+			if err != nil {
+				t.Errorf("[%d] encoding %v with %s: %v", i, tc.in.Value, tc.codec, err)
+				continue
+			}
+			if !bytes.Equal(b, tc.bin) {
+				t.Errorf("[%d] expected %x, got %x", i, tc.bin, b)
+			}
+			v, ok := keyCodecs[tc.codec].decode(b)
+			if !ok {
+				t.Errorf("[%d] decoding %x with %s failed", i, b, tc.codec)
+				continue
+			}
+			if v.Value != tc.out {
+				t.Errorf("[%d] expected %v, got %v", i, tc.out, v.Value)
+			}
+		}
+	})
+
+	t.Run("list and binary", func(t *testing.T) {
+		b, err := keyEncoder("u64be")(nu.Value{Value: []nu.Value{{Value: []byte{1}}, {Value: int64(2)}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := []byte{1, 0, 0, 0, 0, 0, 0, 0, 2}; !bytes.Equal(b, exp) {
+			t.Errorf("expected %x, got %x", exp, b)
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		var testCases = []struct {
+			codec string
+			in    any
+		}{
+			{codec: "u64be", in: int64(-1)},
+			{codec: "u64be", in: "foo"},
+			{codec: "i64be", in: 1.5},
+			{codec: "unixnano", in: "yesterday"},
+			{codec: "uuid", in: "0193a5b8-7c3e"},
+			{codec: "uuid", in: int64(1)},
+			{codec: "ulid", in: "01ARZ3NDEKTSV4RRFFQ69G5FA"},
+			{codec: "ulid", in: "81ARZ3NDEKTSV4RRFFQ69G5FAV"},
+			{codec: "ulid", in: "01ARZ3NDEKTSV4RRFFQ69G5FAU"},
+		}
+		for i, tc := range testCases {
+			if b, err := keyEncoder(tc.codec)(nu.Value{Value: tc.in}); err == nil {
+				t.Errorf("[%d] expected error encoding %v with %s, got %x", i, tc.in, tc.codec, b)
+			}
+		}
+	})
+
+	t.Run("invalid length", func(t *testing.T) {
+		for name, codec := range keyCodecs {
+			if v, ok := codec.decode([]byte("foo")); ok {
+				t.Errorf("%s: expected decoding to fail, got %v", name, v.Value)
+			}
+		}
+		if v, ok := keyCodecs["u64be"].decode(bytes.Repeat([]byte{0xff}, 8)); ok {
+			t.Errorf("expected u64be decoding of value out of int64 range to fail, got %v", v.Value)
+		}
+	})
+}
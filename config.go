@@ -15,10 +15,12 @@ import (
 )
 
 type configuration struct {
-	timeout   time.Duration
-	readOnly  bool
-	fileMode  fs.FileMode
-	mustExist bool // if true only existing files can be opened (ie can't create new DB)
+	timeout    time.Duration
+	readOnly   bool
+	fileMode   fs.FileMode
+	mustExist  bool   // if true only existing files can be opened (ie can't create new DB)
+	backupDir  string // when not empty database is backed up into the directory before modifying it
+	backupKeep int64  // number of the latest backups to keep, 0 = keep all
 }
 
 func (cfg *configuration) parse(v nu.Value) error {
@@ -53,6 +55,20 @@ func (cfg *configuration) parse(v nu.Value) error {
 			if cfg.mustExist, ok = v.Value.(bool); !ok {
 				return expectedBool("mustExist", v)
 			}
+		case "backupDir":
+			if cfg.backupDir, ok = v.Value.(string); !ok {
+				return nu.Error{
+					Err:    fmt.Errorf("expected 'backupDir' to be string, got %T", v.Value),
+					Labels: []nu.Label{{Text: "expected String", Span: v.Span}},
+				}
+			}
+		case "backupKeep":
+			if cfg.backupKeep, ok = v.Value.(int64); !ok || cfg.backupKeep < 0 {
+				return nu.Error{
+					Err:    fmt.Errorf("expected 'backupKeep' to be non-negative integer, got %v", v.Value),
+					Labels: []nu.Label{{Text: "expected non-negative Integer", Span: v.Span}},
+				}
+			}
 		}
 	}
 	return nil
@@ -68,10 +84,11 @@ func expectedBool(name string, v nu.Value) error {
 
 func loadCfg(ctx context.Context, call *nu.ExecCommand) (configuration, error) {
 	cfg := configuration{
-		timeout:   3 * time.Second,
-		readOnly:  false,
-		fileMode:  0600,
-		mustExist: false,
+		timeout:    3 * time.Second,
+		readOnly:   false,
+		fileMode:   0600,
+		mustExist:  false,
+		backupKeep: 10,
 	}
 
 	v, err := call.GetPluginConfig(ctx)
@@ -93,25 +110,45 @@ func openDB(ctx context.Context, call *nu.ExecCommand, action string) (*bbolt.DB
 	if err != nil {
 		return nil, err
 	}
+	// new database doesn't need backup
+	_, statErr := os.Stat(call.Positional[0].Value.(string))
+	// source of the "copy" is never modified and dry-run must not create the database
+	db, err := cfg.open(call.Positional[0], slices.Contains([]string{"add", "set"}, action) && !isDryRun(call), action == "copy")
+	if err != nil {
+		return nil, err
+	}
+	if statErr == nil && modifiesDB(call, action) && !isDryRun(call) {
+		if err := cfg.backup(db); err != nil {
+			return nil, errors.Join(err, db.Close())
+		}
+	}
+	return db, nil
+}
 
-	dbName := call.Positional[0].Value.(string)
+/*
+open opens the database file, canCreate controls is the file allowed to be
+created when it doesn't exist (the "mustExist" configuration flag overrides
+it).
+*/
+func (cfg *configuration) open(file nu.Value, canCreate, readOnly bool) (*bbolt.DB, error) {
+	dbName := file.Value.(string)
 	if _, err := os.Stat(dbName); err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
-			if cfg.mustExist || !slices.Contains([]string{"add", "set"}, action) {
+			if cfg.mustExist || !canCreate {
 				return nil, nu.Error{
 					Err:    fmt.Errorf("database does not exist"),
 					Code:   "boltdb::config::mustExist",
 					Url:    "https://github.com/ainvaltin/nu_plugin_boltdb?tab=readme-ov-file#configuration",
-					Help:   `Only "add" and "set" actions are allowed to create database as the "mustExist" configuration flag is set to "true".`,
-					Labels: []nu.Label{{Text: "file does not exist", Span: call.Positional[0].Span}},
+					Help:   `Only "add" and "set" actions (and "copy" for the destination database) are allowed to create database as the "mustExist" configuration flag is set to "true".`,
+					Labels: []nu.Label{{Text: "file does not exist", Span: file.Span}},
 				}
 			}
 		} else {
-			return nil, nu.Error{Err: fmt.Errorf("invalid database name: %w", err), Labels: []nu.Label{{Text: err.Error(), Span: call.Positional[0].Span}}}
+			return nil, nu.Error{Err: fmt.Errorf("invalid database name: %w", err), Labels: []nu.Label{{Text: err.Error(), Span: file.Span}}}
 		}
 	}
 
-	db, err := bbolt.Open(dbName, cfg.fileMode, &bbolt.Options{Timeout: cfg.timeout, ReadOnly: cfg.readOnly})
+	db, err := bbolt.Open(dbName, cfg.fileMode, &bbolt.Options{Timeout: cfg.timeout, ReadOnly: cfg.readOnly || readOnly})
 	if err != nil {
 		return nil, fmt.Errorf("opening bolt db: %w", err)
 	}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+/*
+copyToDB copies the bucket (recursively) into another database file.
+
+The source database is opened read-only, destination database is created
+when it doesn't exist. By default the destination path is the same as the
+source path, the "dest" flag can be used to copy into different bucket.
+*/
+func copyToDB(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
+	path, _, err := location(call)
+	if err != nil {
+		return err
+	}
+
+	destPath := path
+	if v, ok := call.FlagValue("dest"); ok {
+		if destPath, err = toPath(v); err != nil {
+			return fmt.Errorf("invalid destination bucket name: %w", err)
+		}
+		if len(destPath) == 0 {
+			return (&nu.Error{Err: errors.New("root bucket can't be the destination")}).AddLabel("destination bucket required", v.Span)
+		}
+	}
+	overwrite := boolFlag(call, "overwrite")
+
+	destFile, _ := call.FlagValue("dest-db")
+	if same, err := sameFile(call.Positional[0].Value.(string), destFile.Value.(string)); err != nil {
+		return err
+	} else if same {
+		return nu.Error{
+			Err:    errors.New("destination database is the same as the source database"),
+			Help:   `Use the "move" action to move buckets within the same database.`,
+			Labels: []nu.Label{{Text: "same database as the source", Span: destFile.Span}},
+		}
+	}
+
+	cfg, err := loadCfg(ctx, call)
+	if err != nil {
+		return err
+	}
+	// dry-run must not create the destination database
+	_, statErr := os.Stat(destFile.Value.(string))
+	dstDB, err := cfg.open(destFile, !isDryRun(call), false)
+	if err != nil {
+		return err
+	}
+	defer dstDB.Close()
+	if statErr == nil && !isDryRun(call) {
+		if err := cfg.backup(dstDB); err != nil {
+			return err
+		}
+	}
+
+	var cs copyStats
+	var sent bool
+	err = db.View(func(stx *bbolt.Tx) error {
+		src, err := goToBucket(stx, path)
+		if err != nil {
+			return err
+		}
+		sent, err = update(ctx, dstDB, call, func(dtx *writeTx) error {
+			dst, err := dtx.createBucket(destPath)
+			if err != nil {
+				return err
+			}
+			return cs.copyBucket(dtx, dst, src, overwrite)
+		})
+		return err
+	})
+	if sent || err != nil {
+		return err
+	}
+	return call.ReturnValue(ctx, cs.toValue())
+}
+
+/*
+copyStats counts the items copied by copyBucket.
+*/
+type copyStats struct {
+	keys    int64
+	buckets int64
+	skipped int64 // keys which already existed in the destination and were not overwritten
+}
+
+/*
+copyBucket recursively copies all the keys, nested buckets and sequences
+of the src bucket into the dst bucket.
+
+When overwrite is false the keys which already exist in the dst bucket are
+not modified and the sequence of the dst bucket is set to the greater of
+the two.
+*/
+func (cs *copyStats) copyBucket(tx *writeTx, dst, src *bbolt.Bucket, overwrite bool) error {
+	if seq := src.Sequence(); overwrite || seq > dst.Sequence() {
+		if err := tx.setSequence(dst, seq); err != nil {
+			return err
+		}
+	}
+
+	c := src.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if v != nil {
+			if !overwrite && dst.Get(k) != nil {
+				cs.skipped++
+				continue
+			}
+			if err := tx.put(dst, k, v); err != nil {
+				return fmt.Errorf("writing key %x: %w", k, err)
+			}
+			cs.keys++
+			continue
+		}
+
+		nb, err := tx.createBucketIn(dst, k, true)
+		if err != nil {
+			return fmt.Errorf("creating bucket %x: %w", k, err)
+		}
+		cs.buckets++
+		if err := cs.copyBucket(tx, nb, src.Bucket(k), overwrite); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cs *copyStats) toValue() nu.Value {
+	return nu.Value{Value: nu.Record{
+		"keys":    nu.Value{Value: cs.keys},
+		"buckets": nu.Value{Value: cs.buckets},
+		"skipped": nu.Value{Value: cs.skipped},
+	}}
+}
+
+// sameFile returns true when both file names resolve to the same absolute path.
+func sameFile(a, b string) (bool, error) {
+	a, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	if b, err = filepath.Abs(b); err != nil {
+		return false, err
+	}
+	return a == b, nil
+}
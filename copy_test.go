@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+func Test_copyBucket(t *testing.T) {
+	src := testDB(t, "a", "b")
+	err := src.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("test"))
+		if err := b.SetSequence(10); err != nil {
+			return err
+		}
+		nb, err := b.CreateBucket([]byte("nested"))
+		if err != nil {
+			return err
+		}
+		return nb.Put([]byte("k"), []byte("v"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		overwrite bool
+		stats     copyStats
+		valueOfA  string
+		sequence  uint64
+	}{
+		{overwrite: false, stats: copyStats{keys: 2, buckets: 1, skipped: 1}, valueOfA: "old a", sequence: 20},
+		{overwrite: true, stats: copyStats{keys: 3, buckets: 1}, valueOfA: "value of a", sequence: 10},
+	} {
+		// destination already has key "a" and greater sequence
+		dst := testDB(t)
+		err := dst.Update(func(tx *bbolt.Tx) error {
+			b := tx.Bucket([]byte("test"))
+			if err := b.SetSequence(20); err != nil {
+				return err
+			}
+			return b.Put([]byte("a"), []byte("old a"))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var cs copyStats
+		err = src.View(func(stx *bbolt.Tx) error {
+			return dst.Update(func(dtx *bbolt.Tx) error {
+				return cs.copyBucket(&writeTx{Tx: dtx}, dtx.Bucket([]byte("test")), stx.Bucket([]byte("test")), tc.overwrite)
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cs != tc.stats {
+			t.Errorf("overwrite=%t: expected stats %+v, got %+v", tc.overwrite, tc.stats, cs)
+		}
+
+		err = dst.View(func(tx *bbolt.Tx) error {
+			b := tx.Bucket([]byte("test"))
+			if v := b.Get([]byte("a")); string(v) != tc.valueOfA {
+				t.Errorf("overwrite=%t: unexpected value of key a: %q", tc.overwrite, v)
+			}
+			if v := b.Get([]byte("b")); string(v) != "value of b" {
+				t.Errorf("overwrite=%t: unexpected value of key b: %q", tc.overwrite, v)
+			}
+			if v := b.Bucket([]byte("nested")).Get([]byte("k")); string(v) != "v" {
+				t.Errorf("overwrite=%t: unexpected value of nested key: %q", tc.overwrite, v)
+			}
+			if seq := b.Sequence(); seq != tc.sequence {
+				t.Errorf("overwrite=%t: expected sequence %d, got %d", tc.overwrite, tc.sequence, seq)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
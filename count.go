@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+func count(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
+	path, _, err := location(call)
+	if err != nil {
+		return err
+	}
+	filter, err := getFilter(call)
+	if err != nil {
+		return err
+	}
+	rng, err := getRange(call)
+	if err != nil {
+		return err
+	}
+	recursive := boolFlag(call, "recursive")
+
+	match := func(k, v []byte) bool { return filter(k) }
+
+	return db.View(func(tx *bbolt.Tx) error {
+		buckets, err := findBuckets(tx, path)
+		if err != nil {
+			return err
+		}
+
+		if !hasWildcards(path) {
+			var cnt counts
+			if err := cnt.add(buckets[0].bucket, rng, match, recursive); err != nil {
+				return err
+			}
+			return call.ReturnValue(ctx, cnt.toValue())
+		}
+
+		// path with wildcards - record for each matching bucket
+		out, err := call.ReturnListStream(ctx)
+		if err != nil {
+			return fmt.Errorf("creating result stream: %w", err)
+		}
+		defer close(out)
+
+		format := getFormatter(call)
+		for _, m := range buckets {
+			var cnt counts
+			if err := cnt.add(m.bucket, rng, match, recursive); err != nil {
+				return err
+			}
+			out <- m.tagger(format)(cnt.toValue(), "")
+		}
+		return nil
+	})
+}
+
+/*
+counts of the items in a bucket.
+*/
+type counts struct {
+	keys    int64
+	buckets int64
+	bytes   int64 // total size of the keys and values
+}
+
+/*
+add adds the counts of the items in the range (accepted by match) of the
+bucket b. When recursive is true the content of the nested buckets (which
+are in the range) is added too.
+*/
+func (cnt *counts) add(b *bbolt.Bucket, rng keyRange, match func(k, v []byte) bool, recursive bool) error {
+	_, err := rng.forEach(b.Cursor(), match, func(k, v []byte) error {
+		if v != nil {
+			cnt.keys++
+			cnt.bytes += int64(len(k) + len(v))
+			return nil
+		}
+
+		cnt.buckets++
+		if recursive {
+			return cnt.add(b.Bucket(k), keyRange{}, func(k, v []byte) bool { return true }, true)
+		}
+		return nil
+	})
+	return err
+}
+
+func (cnt *counts) toValue() nu.Value {
+	return nu.Value{Value: nu.Record{
+		"keys":    nu.Value{Value: cnt.keys},
+		"buckets": nu.Value{Value: cnt.buckets},
+		"bytes":   nu.Value{Value: nu.Filesize(cnt.bytes)},
+	}}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+func Test_counts(t *testing.T) {
+	db := testDB(t, "a", "ba", "bb")
+	err := db.Update(func(tx *bbolt.Tx) error {
+		nb, err := tx.Bucket([]byte("test")).CreateBucket([]byte("bc"))
+		if err != nil {
+			return err
+		}
+		if err := nb.Put([]byte("x"), []byte("xx")); err != nil {
+			return err
+		}
+		deep, err := nb.CreateBucket([]byte("deep"))
+		if err != nil {
+			return err
+		}
+		return deep.Put([]byte("y"), []byte("y"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all := func(k, v []byte) bool { return true }
+	var testCases = []struct {
+		name      string
+		rng       keyRange
+		match     func(k, v []byte) bool
+		recursive bool
+		exp       counts
+	}{
+		// keys "a" (1+10 bytes), "ba" and "bb" (2+11 bytes each)
+		{name: "all", match: all, exp: counts{keys: 3, buckets: 1, bytes: 37}},
+		// nested keys "x" (1+2 bytes) and "y" (1+1 bytes)
+		{name: "recursive", match: all, recursive: true, exp: counts{keys: 5, buckets: 2, bytes: 42}},
+		{name: "prefix", rng: keyRange{prefix: []byte("b")}, match: all, exp: counts{keys: 2, buckets: 1, bytes: 26}},
+		{name: "prefix, recursive", rng: keyRange{prefix: []byte("b")}, match: all, recursive: true, exp: counts{keys: 4, buckets: 2, bytes: 31}},
+		{name: "range", rng: keyRange{from: []byte("b"), to: []byte("bc")}, match: all, recursive: true, exp: counts{keys: 2, buckets: 0, bytes: 26}},
+		{name: "range inclusive", rng: keyRange{from: []byte("bb"), to: []byte("bc"), toIncl: true}, match: all, exp: counts{keys: 1, buckets: 1, bytes: 13}},
+		{name: "filter", match: func(k, v []byte) bool { return !bytes.Equal(k, []byte("bb")) }, exp: counts{keys: 2, buckets: 1, bytes: 24}},
+	}
+
+	err = db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("test"))
+		for _, tc := range testCases {
+			var cnt counts
+			if err := cnt.add(b, tc.rng, tc.match, tc.recursive); err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+				continue
+			}
+			if cnt != tc.exp {
+				t.Errorf("%s: expected %+v, got %+v", tc.name, tc.exp, cnt)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+/*
+getValueDecoder returns decoder for the values based on the "value-format"
+flag. Nil is returned when values should be returned as binary.
+*/
+func getValueDecoder(call *nu.ExecCommand) func(v []byte) (nu.Value, error) {
+	v, ok := call.FlagValue("value-format")
+	if !ok {
+		return nil
+	}
+	switch v.Value.(string) {
+	case "json":
+		return decodeJSON
+	case "msgpack":
+		return decodeMsgpack
+	case "gob":
+		return decodeGob
+	case "text":
+		return func(v []byte) (nu.Value, error) {
+			if !utf8.Valid(v) {
+				return nu.Value{}, errors.New("value is not valid UTF-8 text")
+			}
+			return nu.Value{Value: string(v)}, nil
+		}
+	case "hex":
+		return func(v []byte) (nu.Value, error) { return nu.Value{Value: fmt.Sprintf("%x", v)}, nil }
+	case "stringify":
+		return func(v []byte) (nu.Value, error) { return stringifyName(v), nil }
+	}
+	return nil
+}
+
+/*
+setValueField sets the "value" field of the row. When the decoder fails the
+value is returned as binary and the error message is added as "error" field,
+so single undecodable value doesn't abort the whole stream.
+*/
+func setValueField(row nu.Record, v []byte, decode func([]byte) (nu.Value, error)) {
+	if decode == nil {
+		row["value"] = nu.Value{Value: slices.Clone(v)}
+		return
+	}
+	dv, err := decode(v)
+	if err != nil {
+		row["value"] = nu.Value{Value: slices.Clone(v)}
+		row["error"] = nu.Value{Value: err.Error()}
+		return
+	}
+	row["value"] = dv
+}
+
+func decodeJSON(data []byte) (nu.Value, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nu.Value{}, fmt.Errorf("decoding JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nu.Value{}, errors.New("decoding JSON: unexpected data after the value")
+	}
+	return toNuValue(v), nil
+}
+
+func decodeMsgpack(data []byte) (nu.Value, error) {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	// maps might have non-string keys
+	dec.SetMapDecoder(func(d *msgpack.Decoder) (any, error) { return d.DecodeUntypedMap() })
+	v, err := dec.DecodeInterface()
+	if err != nil {
+		return nu.Value{}, fmt.Errorf("decoding msgpack: %w", err)
+	}
+	return toNuValue(v), nil
+}
+
+/*
+toNuValue converts value returned by generic decoder (ie unmarshaling into
+"any") to Nu value.
+*/
+func toNuValue(v any) nu.Value {
+	switch t := v.(type) {
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return nu.Value{Value: n}
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return nu.Value{Value: t.String()}
+		}
+		return nu.Value{Value: f}
+	case []any:
+		items := make([]nu.Value, len(t))
+		for i, v := range t {
+			items[i] = toNuValue(v)
+		}
+		return nu.Value{Value: items}
+	case map[string]any:
+		r := make(nu.Record, len(t))
+		for k, v := range t {
+			r[k] = toNuValue(v)
+		}
+		return nu.Value{Value: r}
+	case map[any]any:
+		r := make(nu.Record, len(t))
+		for k, v := range t {
+			r[recordKey(k)] = toNuValue(v)
+		}
+		return nu.Value{Value: r}
+	default:
+		return nu.ToValue(v)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+func Test_decodeJSON(t *testing.T) {
+	v, err := decodeJSON([]byte(`{"id": 42, "ratio": 0.5, "tags": ["a"], "ok": true, "none": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := nu.Record{
+		"id":    nu.Value{Value: int64(42)},
+		"ratio": nu.Value{Value: 0.5},
+		"tags":  nu.Value{Value: []nu.Value{{Value: "a"}}},
+		"ok":    nu.Value{Value: true},
+		"none":  nu.Value{},
+	}
+	if !reflect.DeepEqual(v.Value, expected) {
+		t.Errorf("expected %#v, got %#v", expected, v.Value)
+	}
+
+	if _, err := decodeJSON([]byte(`{"id": 1} garbage`)); err == nil {
+		t.Error("expected error for trailing data")
+	}
+}
+
+func Test_decodeMsgpack(t *testing.T) {
+	data, err := msgpack.Marshal(map[any]any{"name": "foo", 1: []int{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := decodeMsgpack(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := v.Value.(nu.Record)
+	if r["name"].Value != "foo" {
+		t.Errorf("unexpected name: %#v", r["name"].Value)
+	}
+	if items, ok := r["1"].Value.([]nu.Value); !ok || len(items) != 2 || items[1].Value != int64(2) {
+		t.Errorf("unexpected list: %#v", r["1"].Value)
+	}
+}
+
+func Test_setValueField(t *testing.T) {
+	row := nu.Record{}
+	setValueField(row, []byte("not json"), decodeJSON)
+	if _, ok := row["error"]; !ok {
+		t.Error("expected error column for undecodable value")
+	}
+	if !reflect.DeepEqual(row["value"].Value, []byte("not json")) {
+		t.Errorf("expected raw value, got %#v", row["value"].Value)
+	}
+
+	row = nu.Record{}
+	setValueField(row, []byte(`"foo"`), decodeJSON)
+	if _, ok := row["error"]; ok || row["value"].Value != "foo" {
+		t.Errorf("unexpected row: %#v", row)
+	}
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 
 	"go.etcd.io/bbolt"
 
@@ -13,20 +16,180 @@ func delete(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
 	if err != nil {
 		return err
 	}
+	filter, err := getFilter(call)
+	if err != nil {
+		return err
+	}
+	rng, err := getRange(call)
+	if err != nil {
+		return err
+	}
+	valueFilter, byValue, err := getValueFilter(call)
+	if err != nil {
+		return err
+	}
+	wholeBucket, err := deletesBucket(call.Named, getKeyEncoder(call))
+	if err != nil {
+		return err
+	}
 
-	return db.Update(func(tx *bbolt.Tx) error {
-		if key == nil {
-			b, err := goToBucket(tx, path[:len(path)-1])
+	if wholeBucket {
+		if len(path) == 0 {
+			return nu.Error{
+				Err:    errors.New("the root bucket can't be deleted"),
+				Help:   "Bucket path must contain at least one item",
+				Labels: []nu.Label{{Text: "empty bucket path", Span: call.Named["bucket"].Span}},
+			}
+		}
+		_, err := update(ctx, db, call, func(tx *writeTx) error {
+			parents, err := tx.findBuckets(path[:len(path)-1])
 			if err != nil {
 				return err
 			}
-			return b.DeleteBucket(path[len(path)-1].name)
-		}
+			for _, m := range parents {
+				if err := deleteBuckets(tx, m.bucket, path[len(path)-1], hasWildcards(path)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return err
+	}
 
-		b, err := goToBucket(tx, path)
+	match := keysOnly(filter, valueFilter)
+	if key != nil {
+		_, err := update(ctx, db, call, func(tx *writeTx) error {
+			buckets, err := tx.findBuckets(path)
+			if err != nil {
+				return err
+			}
+			for _, m := range buckets {
+				if byValue {
+					if v := m.bucket.Get(key.name); v == nil || !valueFilter(v) {
+						continue
+					}
+				}
+				if err := tx.deleteKey(m.bucket, key.name); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return err
+	}
+
+	var cnt int64
+	sent, err := update(ctx, db, call, func(tx *writeTx) error {
+		buckets, err := tx.findBuckets(path)
 		if err != nil {
 			return err
 		}
-		return b.Delete(key.name)
+		for _, m := range buckets {
+			n, err := deleteKeys(tx, m.bucket, rng, match)
+			if err != nil {
+				return err
+			}
+			cnt += n
+		}
+		return nil
 	})
+	if sent || err != nil {
+		return err
+	}
+	return call.ReturnValue(ctx, nu.Value{Value: cnt})
+}
+
+// flags which make "delete" to delete keys rather than the bucket
+var keySelectors = []string{"key", "match", "prefix", "from", "to", "through", "match-value", "value-contains"}
+
+/*
+deletesBucket returns true when none of the key selection flags is given, ie
+"delete" should delete the bucket. The decision is made by the presence of the
+flags as empty selector would otherwise select the whole bucket - empty range,
+prefix and value selectors are rejected.
+*/
+func deletesBucket(named nu.NamedParams, encode func(nu.Value) ([]byte, error)) (bool, error) {
+	bucket := true
+	for _, name := range keySelectors {
+		v, ok := named[name]
+		if !ok {
+			continue
+		}
+		bucket = false
+
+		var empty bool
+		switch name {
+		case "key", "match":
+			continue
+		case "match-value":
+			empty = v.Value == ""
+		case "value-contains":
+			b, err := toBytes(v)
+			empty = err == nil && len(b) == 0
+		default:
+			b, err := encode(v)
+			empty = err == nil && len(b) == 0
+		}
+		if empty {
+			return false, nu.Error{
+				Err:    fmt.Errorf("the %q flag must not be empty", name),
+				Help:   "Empty selector would select all the keys, to delete the bucket omit the key selection flags",
+				Labels: []nu.Label{{Text: "empty selector", Span: v.Span}},
+			}
+		}
+	}
+	return bucket, nil
+}
+
+/*
+deleteBuckets deletes nested bucket(s) matching the item from the parent.
+When "optional" is true it is not an error when the bucket doesn't exist.
+*/
+func deleteBuckets(tx *writeTx, parent *bbolt.Bucket, item boltItem, optional bool) error {
+	if item.match == nil {
+		if optional && parent.Bucket(item.name) == nil {
+			return nil
+		}
+		return tx.deleteBucket(parent, item.name)
+	}
+
+	var names [][]byte
+	err := parent.ForEachBucket(func(k []byte) error {
+		if item.match(k) {
+			names = append(names, slices.Clone(k))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := tx.deleteBucket(parent, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+deleteKeys deletes all the keys in the range accepted by the match func.
+Returns the number of deleted keys.
+*/
+func deleteKeys(tx *writeTx, b *bbolt.Bucket, rng keyRange, match func(k, v []byte) bool) (int64, error) {
+	// deleting while iterating with cursor might skip items so collect the keys first
+	var keys [][]byte
+	_, err := rng.forEach(b.Cursor(), match, func(k, v []byte) error {
+		keys = append(keys, slices.Clone(k))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, k := range keys {
+		if err := tx.deleteKey(b, k); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(keys)), nil
 }
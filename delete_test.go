@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+func Test_deleteKeys(t *testing.T) {
+	db := testDB(t, "a", "ba", "bb", "bc", "c")
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("test"))
+		// nested bucket with the prefix must not be deleted
+		if _, err := b.CreateBucket([]byte("bd")); err != nil {
+			return err
+		}
+
+		all := func([]byte) bool { return true }
+		n, err := deleteKeys(&writeTx{Tx: tx}, b, keyRange{prefix: []byte("b")}, keysOnly(func(k []byte) bool { return !bytes.Equal(k, []byte("bb")) }, all))
+		if err != nil {
+			return err
+		}
+		if n != 2 {
+			t.Errorf("expected 2 keys to be deleted, got %d", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := collectKeys(t, db, keyRange{}); !slices.Equal(keys, []string{"a", "bb", "bd", "c"}) {
+		t.Errorf("unexpected keys after delete: %q", keys)
+	}
+}
+
+func Test_deleteBuckets(t *testing.T) {
+	db := testDB(t, "a")
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("test"))
+		for _, name := range []string{"foo", "bar", "baz"} {
+			if _, err := b.CreateBucket([]byte(name)); err != nil {
+				return err
+			}
+		}
+
+		if err := deleteBuckets(&writeTx{Tx: tx}, b, boltItem{name: []byte("foo")}, false); err != nil {
+			t.Errorf("deleting existing bucket: %v", err)
+		}
+		if err := deleteBuckets(&writeTx{Tx: tx}, b, boltItem{name: []byte("foo")}, false); err == nil {
+			t.Error("expected error when deleting non-existing bucket")
+		}
+		if err := deleteBuckets(&writeTx{Tx: tx}, b, boltItem{name: []byte("foo")}, true); err != nil {
+			t.Errorf("deleting non-existing optional bucket: %v", err)
+		}
+		if keys := collectBucketKeys(b); !slices.Equal(keys, []string{"a", "bar", "baz"}) {
+			t.Errorf("unexpected items after deleting bucket: %q", keys)
+		}
+
+		if err := deleteBuckets(&writeTx{Tx: tx}, b, boltItem{match: regexp.MustCompile("^ba").Match}, false); err != nil {
+			t.Errorf("deleting buckets by wildcard: %v", err)
+		}
+		// the key "a" must not be affected
+		if keys := collectBucketKeys(b); !slices.Equal(keys, []string{"a"}) {
+			t.Errorf("unexpected items after deleting buckets by wildcard: %q", keys)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func collectBucketKeys(b *bbolt.Bucket) (keys []string) {
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		keys = append(keys, string(k))
+	}
+	return keys
+}
+
+func Test_deletesBucket(t *testing.T) {
+	span := nu.Span{Start: 20, End: 22}
+	var testCases = []struct {
+		name   string
+		named  nu.NamedParams
+		bucket bool
+		err    string
+	}{
+		{name: "no selectors", named: nu.NamedParams{"bucket": {Value: "x"}}, bucket: true},
+		{name: "key", named: nu.NamedParams{"key": {Value: "k"}}},
+		{name: "match", named: nu.NamedParams{"match": {Value: ""}}},
+		{name: "prefix", named: nu.NamedParams{"prefix": {Value: "b"}}},
+		// delete -b x --prefix [] must not drop bucket x
+		{name: "empty prefix", named: nu.NamedParams{"bucket": {Value: "x"}, "prefix": {Value: []nu.Value{}, Span: span}}, err: `the "prefix" flag must not be empty`},
+		{name: "empty prefix string", named: nu.NamedParams{"prefix": {Value: "", Span: span}}, err: `the "prefix" flag must not be empty`},
+		{name: "empty from", named: nu.NamedParams{"from": {Value: []byte{}, Span: span}}, err: `the "from" flag must not be empty`},
+		{name: "empty to", named: nu.NamedParams{"from": {Value: "a"}, "to": {Value: []nu.Value{}, Span: span}}, err: `the "to" flag must not be empty`},
+		{name: "empty through", named: nu.NamedParams{"through": {Value: "", Span: span}}, err: `the "through" flag must not be empty`},
+		{name: "empty value regexp", named: nu.NamedParams{"match-value": {Value: "", Span: span}}, err: `the "match-value" flag must not be empty`},
+		{name: "empty value substring", named: nu.NamedParams{"value-contains": {Value: []nu.Value{}, Span: span}}, err: `the "value-contains" flag must not be empty`},
+	}
+	for _, tc := range testCases {
+		bucket, err := deletesBucket(tc.named, toBytes)
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			} else if bucket != tc.bucket {
+				t.Errorf("%s: expected %t, got %t", tc.name, tc.bucket, bucket)
+			}
+			continue
+		}
+		var nuErr nu.Error
+		if !errors.As(err, &nuErr) || nuErr.Err.Error() != tc.err || len(nuErr.Labels) != 1 || nuErr.Labels[0].Span != span {
+			t.Errorf("%s: expected labeled error %q, got %v", tc.name, tc.err, err)
+		}
+	}
+
+	t.Run("codec", func(t *testing.T) {
+		named := nu.NamedParams{"prefix": {Value: []nu.Value{}, Span: span}}
+		if _, err := deletesBucket(named, keyEncoder("u64be")); err == nil {
+			t.Error("expected error for empty prefix with key codec")
+		}
+	})
+
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"slices"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+/*
+update executes fn in a write transaction. When the "dry-run" flag is set
+the transaction is always rolled back and summary of the changes fn made is
+returned as the result of the command, in that case "sent" is true and the
+action must not return it's own result.
+*/
+func update(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand, fn func(tx *writeTx) error) (sent bool, err error) {
+	if !isDryRun(call) {
+		return false, db.Update(func(tx *bbolt.Tx) error { return fn(&writeTx{Tx: tx}) })
+	}
+
+	tx, err := db.Begin(true)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	log := newChangeLog(getFormatter(call))
+	if err := fn(&writeTx{Tx: tx, log: log}); err != nil {
+		return false, err
+	}
+	return true, call.ReturnValue(ctx, log.toValue())
+}
+
+/*
+isDryRun returns true when the "dry-run" flag is set.
+*/
+func isDryRun(call *nu.ExecCommand) bool {
+	return boolFlag(call, "dry-run")
+}
+
+/*
+writeTx is the write transaction update hands to the callback. In dry-run
+mode log is not nil and the changes are recorded into it.
+
+Mutations must go through the helper methods (put, deleteKey, createBucketIn,
+deleteBucket, setSequence, nextSequence) for the changes to be recorded and
+buckets should be looked up using the goToBucket, findBuckets and
+createBucket methods so that the changes can be reported with the path of
+the bucket.
+*/
+type writeTx struct {
+	*bbolt.Tx
+	log *changeLog
+}
+
+/*
+changeLog records the changes made in the dry-run transaction.
+*/
+type changeLog struct {
+	format func([]byte) nu.Value
+	paths  map[*bbolt.Bucket][][]byte // path of the buckets used in the transaction
+
+	created        []nu.Value
+	written        []nu.Value
+	deleted        []nu.Value
+	deletedBuckets []nu.Value
+	sequences      []nu.Value
+}
+
+func newChangeLog(format func([]byte) nu.Value) *changeLog {
+	return &changeLog{format: format, paths: map[*bbolt.Bucket][][]byte{}}
+}
+
+/*
+notePath records the path of the bucket so that changes of the bucket can be
+reported with the path. Write transaction caches the buckets so the same
+*bbolt.Bucket is returned for the path during the transaction.
+*/
+func (tx *writeTx) notePath(b *bbolt.Bucket, path [][]byte) {
+	if tx.log != nil {
+		tx.log.paths[b] = slices.Clone(path)
+	}
+}
+
+func (tx *writeTx) goToBucket(path []boltItem) (*bbolt.Bucket, error) {
+	b, err := goToBucket(tx.Tx, path)
+	if err == nil {
+		tx.notePath(b, itemNames(path))
+	}
+	return b, err
+}
+
+func (tx *writeTx) findBuckets(path []boltItem) ([]bucketMatch, error) {
+	r, err := findBuckets(tx.Tx, path)
+	for _, m := range r {
+		tx.notePath(m.bucket, m.path)
+	}
+	return r, err
+}
+
+func (log *changeLog) path(b *bbolt.Bucket, name ...[]byte) nu.Value {
+	path := append(slices.Clip(log.paths[b]), name...)
+	r := make([]nu.Value, len(path))
+	for i, v := range path {
+		r[i] = log.format(v)
+	}
+	return nu.Value{Value: r}
+}
+
+func sizeOf(v []byte) nu.Value {
+	if v == nil {
+		return nu.Value{}
+	}
+	return nu.Value{Value: nu.Filesize(len(v))}
+}
+
+func (log *changeLog) toValue() nu.Value {
+	return nu.Value{Value: nu.Record{
+		"created":         nu.Value{Value: log.created},
+		"written":         nu.Value{Value: log.written},
+		"deleted":         nu.Value{Value: log.deleted},
+		"deleted_buckets": nu.Value{Value: log.deletedBuckets},
+		"sequences":       nu.Value{Value: log.sequences},
+		"changed": nu.Value{Value: nu.Record{
+			"keys":    nu.Value{Value: int64(len(log.written) + len(log.deleted))},
+			"buckets": nu.Value{Value: int64(len(log.created) + len(log.deletedBuckets))},
+		}},
+	}}
+}
+
+func (tx *writeTx) put(b *bbolt.Bucket, key, value []byte) error {
+	if log := tx.log; log != nil {
+		log.written = append(log.written, nu.Value{Value: nu.Record{
+			"bucket":   log.path(b),
+			"key":      log.format(key),
+			"old_size": sizeOf(b.Get(key)),
+			"new_size": sizeOf(value),
+		}})
+	}
+	return b.Put(key, value)
+}
+
+func (tx *writeTx) deleteKey(b *bbolt.Bucket, key []byte) error {
+	if log := tx.log; log != nil {
+		if v := b.Get(key); v != nil {
+			log.deleted = append(log.deleted, nu.Value{Value: nu.Record{
+				"bucket": log.path(b),
+				"key":    log.format(key),
+				"size":   sizeOf(v),
+			}})
+		}
+	}
+	return b.Delete(key)
+}
+
+/*
+createBucketIn creates nested bucket "name" in the parent bucket, when
+ifNotExists is true existing bucket is returned.
+*/
+func (tx *writeTx) createBucketIn(parent *bbolt.Bucket, name []byte, ifNotExists bool) (*bbolt.Bucket, error) {
+	log := tx.log
+	if log != nil && ifNotExists {
+		if b := parent.Bucket(name); b != nil {
+			log.paths[b] = append(slices.Clip(log.paths[parent]), slices.Clone(name))
+			return b, nil
+		}
+	}
+
+	var b *bbolt.Bucket
+	var err error
+	if ifNotExists {
+		b, err = parent.CreateBucketIfNotExists(name)
+	} else {
+		b, err = parent.CreateBucket(name)
+	}
+	if err != nil || log == nil {
+		return b, err
+	}
+	log.paths[b] = append(slices.Clip(log.paths[parent]), slices.Clone(name))
+	log.created = append(log.created, log.path(b))
+	return b, nil
+}
+
+func (tx *writeTx) deleteBucket(parent *bbolt.Bucket, name []byte) error {
+	if log := tx.log; log != nil && parent.Bucket(name) != nil {
+		log.deletedBuckets = append(log.deletedBuckets, log.path(parent, name))
+	}
+	return parent.DeleteBucket(name)
+}
+
+func (tx *writeTx) setSequence(b *bbolt.Bucket, seq uint64) error {
+	if log := tx.log; log != nil && b.Sequence() != seq {
+		log.sequences = append(log.sequences, nu.Value{Value: nu.Record{
+			"bucket": log.path(b),
+			"old":    nu.ToValue(b.Sequence()),
+			"new":    nu.ToValue(seq),
+		}})
+	}
+	return b.SetSequence(seq)
+}
+
+func (tx *writeTx) nextSequence(b *bbolt.Bucket) (uint64, error) {
+	old := b.Sequence()
+	seq, err := b.NextSequence()
+	if log := tx.log; log != nil && err == nil {
+		log.sequences = append(log.sequences, nu.Value{Value: nu.Record{
+			"bucket": log.path(b),
+			"old":    nu.ToValue(old),
+			"new":    nu.ToValue(seq),
+		}})
+	}
+	return seq, err
+}
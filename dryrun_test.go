@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+func Test_changeLog(t *testing.T) {
+	db := testDB(t, "a", "b")
+
+	tx, err := db.Begin(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	log := newChangeLog(func(b []byte) nu.Value { return nu.Value{Value: string(b)} })
+	wtx := &writeTx{Tx: tx, log: log}
+
+	b, err := wtx.goToBucket([]boltItem{{name: []byte("test")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wtx.put(b, []byte("a"), []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := wtx.put(b, []byte("c"), []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := wtx.deleteKey(b, []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	// deleting non-existing key is not a change
+	if err := wtx.deleteKey(b, []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	nb, err := wtx.createBucketIn(b, []byte("nested"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wtx.setSequence(nb, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := wtx.deleteBucket(b, []byte("nested")); err != nil {
+		t.Fatal(err)
+	}
+	// deleting non-existing bucket fails and is not a change
+	if err := wtx.deleteBucket(b, []byte("nested")); err == nil {
+		t.Error("expected error when deleting non-existing bucket")
+	}
+
+	if len(log.written) != 2 || len(log.deleted) != 1 || len(log.created) != 1 || len(log.sequences) != 1 || len(log.deletedBuckets) != 1 {
+		t.Fatalf("unexpected number of changes: %d written, %d deleted, %d created, %d sequences, %d deleted buckets", len(log.written), len(log.deleted), len(log.created), len(log.sequences), len(log.deletedBuckets))
+	}
+	w := log.written[0].Value.(nu.Record)
+	if w["old_size"].Value != nu.Filesize(10) || w["new_size"].Value != nu.Filesize(3) {
+		t.Errorf("unexpected sizes of the overwritten key: %v -> %v", w["old_size"].Value, w["new_size"].Value)
+	}
+	if w := log.written[1].Value.(nu.Record); w["old_size"].Value != nil {
+		t.Errorf("expected new key not to have old size, got %v", w["old_size"].Value)
+	}
+	path := log.created[0].Value.([]nu.Value)
+	if len(path) != 2 || path[0].Value != "test" || path[1].Value != "nested" {
+		t.Errorf("unexpected path of the created bucket: %v", path)
+	}
+	if path := log.deletedBuckets[0].Value.([]nu.Value); len(path) != 2 || path[0].Value != "test" || path[1].Value != "nested" {
+		t.Errorf("unexpected path of the deleted bucket: %v", path)
+	}
+	if s := log.sequences[0].Value.(nu.Record); len(s["bucket"].Value.([]nu.Value)) != 2 {
+		t.Errorf("unexpected bucket of the sequence change: %v", s["bucket"].Value)
+	}
+
+	// 2 written + 1 deleted keys, 1 created + 1 deleted bucket
+	changed := log.toValue().Value.(nu.Record)["changed"].Value.(nu.Record)
+	if changed["keys"].Value != int64(3) || changed["buckets"].Value != int64(2) {
+		t.Errorf("unexpected counts of changes: %v", changed)
+	}
+
+	// without change log nothing is recorded
+	plain := &writeTx{Tx: tx}
+	if err := plain.put(b, []byte("d"), []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if len(log.written) != 2 {
+		t.Errorf("expected write outside of dry-run not to be recorded, got %d writes", len(log.written))
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+/*
+exists returns true when the database, bucket and key (when given) exist.
+Unlike other actions missing database or bucket is not an error.
+*/
+func exists(ctx context.Context, call *nu.ExecCommand) error {
+	path, key, err := location(call)
+	if err != nil {
+		return err
+	}
+
+	found, err := isPresent(call.Positional[0].Value.(string), func() (*bbolt.DB, error) { return openDB(ctx, call, "exists") }, path, key)
+	if err != nil {
+		return err
+	}
+	return call.ReturnValue(ctx, nu.Value{Value: found})
+}
+
+/*
+isPresent checks does the item exist in the database file, the database is
+opened (using the "open" func) only when the file exists.
+*/
+func isPresent(file string, open func() (*bbolt.DB, error), path []boltItem, key *boltItem) (bool, error) {
+	if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	db, err := open()
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	found := false
+	err = db.View(func(tx *bbolt.Tx) error {
+		b := tx.Cursor().Bucket()
+		for _, v := range path {
+			if v.match != nil {
+				return errWildcard(v)
+			}
+			if b = b.Bucket(v.name); b == nil {
+				return nil
+			}
+		}
+
+		// Get returns nil for nested buckets so key must be real key
+		found = key == nil || b.Get(key.name) != nil
+		return nil
+	})
+	return found, err
+}
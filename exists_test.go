@@ -0,0 +1,77 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+func Test_isPresent(t *testing.T) {
+	db := testDB(t, "a")
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.Bucket([]byte("test")).CreateBucket([]byte("sub"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// isPresent closes the database so reopen it for every case
+	file := db.Path()
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	open := func() (*bbolt.DB, error) { return bbolt.Open(file, 0600, nil) }
+
+	item := func(name string) boltItem { return boltItem{name: []byte(name)} }
+	bucket := []boltItem{item("test")}
+	key := func(name string) *boltItem { v := item(name); return &v }
+
+	var testCases = []struct {
+		name string
+		path []boltItem
+		key  *boltItem
+		exp  bool
+	}{
+		{name: "bucket", path: bucket, exp: true},
+		{name: "key", path: bucket, key: key("a"), exp: true},
+		{name: "missing bucket", path: []boltItem{item("test"), item("none")}, exp: false},
+		{name: "missing root bucket", path: []boltItem{item("none")}, key: key("a"), exp: false},
+		{name: "missing key", path: bucket, key: key("b"), exp: false},
+		{name: "nested bucket as key", path: bucket, key: key("sub"), exp: false},
+		{name: "nested bucket", path: []boltItem{item("test"), item("sub")}, exp: true},
+	}
+	for _, tc := range testCases {
+		found, err := isPresent(file, open, tc.path, tc.key)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if found != tc.exp {
+			t.Errorf("%s: expected %t, got %t", tc.name, tc.exp, found)
+		}
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		missing := filepath.Join(t.TempDir(), "missing.db")
+		found, err := isPresent(missing, func() (*bbolt.DB, error) {
+			t.Error("database must not be opened when the file doesn't exist")
+			return bbolt.Open(missing, 0600, nil)
+		}, bucket, key("a"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found {
+			t.Error("expected false for missing database file")
+		}
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		path := []boltItem{item("test"), {match: func([]byte) bool { return true }}}
+		_, err := isPresent(file, open, path, nil)
+		if err == nil || !strings.Contains(err.Error(), "wildcards in the bucket path are not supported") {
+			t.Errorf("expected wildcard error, got %v", err)
+		}
+	})
+}
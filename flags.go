@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"regexp"
 	"slices"
@@ -16,7 +18,7 @@ func location(call *nu.ExecCommand) (bucket []boltItem, key *boltItem, err error
 	}
 
 	if b, ok := call.FlagValue("key"); ok {
-		k, err := toBytes(b)
+		k, err := getKeyEncoder(call)(b)
 		if err != nil {
 			return nil, nil, fmt.Errorf("invalid key name: %w", err)
 		}
@@ -34,17 +36,62 @@ func getFilter(call *nu.ExecCommand) (func(key []byte) bool, error) {
 
 	reg, err := regexp.Compile(match.Value.(string))
 	if err != nil {
-		return nil, nu.Error{
-			Err:    fmt.Errorf("compiling regular expression: %w", err),
-			Code:   "go::regexp::syntax",
-			Url:    "https://pkg.go.dev/regexp/syntax",
-			Help:   "See Go documentation about supported regular expression syntax",
-			Labels: []nu.Label{{Text: "invalid regexp", Span: match.Span}},
-		}
+		return nil, regexpError(err, match.Span)
 	}
 	return func(key []byte) bool { return reg.Match(key) }, nil
 }
 
+/*
+getValueFilter returns filter based on the "match-value" and "value-contains"
+flags. The flag "active" is true when at least one of the flags was given.
+*/
+func getValueFilter(call *nu.ExecCommand) (_ func(value []byte) bool, active bool, _ error) {
+	var match, contains *nu.Value
+	if v, ok := call.FlagValue("match-value"); ok {
+		match = &v
+	}
+	if v, ok := call.FlagValue("value-contains"); ok {
+		contains = &v
+	}
+	return valueFilter(match, contains)
+}
+
+/*
+valueFilter builds the value filter out of (optional) "match-value" and
+"value-contains" flag values.
+*/
+func valueFilter(match, contains *nu.Value) (_ func(value []byte) bool, active bool, _ error) {
+	var reg *regexp.Regexp
+	if match != nil {
+		var err error
+		if reg, err = regexp.Compile(match.Value.(string)); err != nil {
+			return nil, false, regexpError(err, match.Span)
+		}
+	}
+
+	var sub []byte
+	if contains != nil {
+		var err error
+		if sub, err = toBytes(*contains); err != nil {
+			return nil, false, fmt.Errorf("invalid value substring: %w", err)
+		}
+	}
+
+	return func(value []byte) bool {
+		return (reg == nil || reg.Match(value)) && (sub == nil || bytes.Contains(value, sub))
+	}, reg != nil || sub != nil, nil
+}
+
+func regexpError(err error, span nu.Span) error {
+	return nu.Error{
+		Err:    fmt.Errorf("compiling regular expression: %w", err),
+		Code:   "go::regexp::syntax",
+		Url:    "https://pkg.go.dev/regexp/syntax",
+		Help:   "See Go documentation about supported regular expression syntax",
+		Labels: []nu.Label{{Text: "invalid regexp", Span: span}},
+	}
+}
+
 func getFormatter(call *nu.ExecCommand) func([]byte) nu.Value {
 	// the default is native/binary format
 	format := func(name []byte) nu.Value { return nu.Value{Value: slices.Clone(name)} }
@@ -63,5 +110,110 @@ func getFormatter(call *nu.ExecCommand) func([]byte) nu.Value {
 	case "HEX":
 		return func(b []byte) nu.Value { return nu.Value{Value: fmt.Sprintf("%X", b)} }
 	}
+	if codec, ok := keyCodecs[fmtFlag.Value.(string)]; ok {
+		// names which are not valid for the codec are returned as binary
+		return func(b []byte) nu.Value {
+			if v, ok := codec.decode(b); ok {
+				return v
+			}
+			return format(b)
+		}
+	}
 	return format
 }
+
+/*
+getRange returns the key range based on the "from", "to", "through",
+"prefix", "after", "reverse", "skip", "limit" and "continuation" flags.
+The keys are encoded using the "key-codec" flag.
+*/
+func getRange(call *nu.ExecCommand) (r keyRange, err error) {
+	encode := getKeyEncoder(call)
+	if v, ok := call.FlagValue("from"); ok {
+		if r.from, err = encode(v); err != nil {
+			return r, fmt.Errorf("invalid range start: %w", err)
+		}
+	}
+	if v, ok := call.FlagValue("to"); ok {
+		if r.to, err = encode(v); err != nil {
+			return r, fmt.Errorf("invalid range end: %w", err)
+		}
+	}
+	if v, ok := call.FlagValue("through"); ok {
+		if r.to, err = encode(v); err != nil {
+			return r, fmt.Errorf("invalid range end: %w", err)
+		}
+		r.toIncl = true
+	}
+	if v, ok := call.FlagValue("prefix"); ok {
+		if r.prefix, err = encode(v); err != nil {
+			return r, fmt.Errorf("invalid prefix: %w", err)
+		}
+	}
+	if v, ok := call.FlagValue("after"); ok {
+		if r.after, err = encode(v); err != nil {
+			return r, fmt.Errorf("invalid \"after\" key: %w", err)
+		}
+	}
+	r.token = boolFlag(call, "continuation")
+	r.reverse = boolFlag(call, "reverse")
+	if v, ok := call.FlagValue("skip"); ok {
+		if r.skip, err = nonNegative(v); err != nil {
+			return r, err
+		}
+	}
+	if v, ok := call.FlagValue("limit"); ok {
+		// zero limit would mean "no limit" for the keyRange
+		if r.limit = v.Value.(int64); r.limit <= 0 {
+			return r, nu.Error{
+				Err:    errors.New("limit must be greater than zero"),
+				Labels: []nu.Label{{Text: "invalid limit", Span: v.Span}},
+			}
+		}
+	}
+	return r, nil
+}
+
+/*
+boolFlag returns the value of the toggle flag, false when the flag is not given.
+*/
+func boolFlag(call *nu.ExecCommand, name string) bool {
+	v, ok := call.FlagValue(name)
+	return ok && v.Value.(bool)
+}
+
+func nonNegative(v nu.Value) (int64, error) {
+	if n := v.Value.(int64); n >= 0 {
+		return n, nil
+	}
+	return 0, nu.Error{
+		Err:    errors.New("value must not be negative"),
+		Labels: []nu.Label{{Text: "negative value", Span: v.Span}},
+	}
+}
+
+/*
+getFillPercent returns the value of the "fill-percent" flag, zero when the
+flag is not given (ie the bucket's default should be used).
+*/
+func getFillPercent(call *nu.ExecCommand) (float64, error) {
+	v, ok := call.FlagValue("fill-percent")
+	if !ok {
+		return 0, nil
+	}
+	var fill float64
+	switch n := v.Value.(type) {
+	case float64:
+		fill = n
+	case int64:
+		fill = float64(n)
+	}
+	if fill < 0.1 || fill > 1 {
+		return 0, nu.Error{
+			Err:    fmt.Errorf("fill percent must be in the range 0.1 ... 1.0, got %v", v.Value),
+			Help:   "Use 1.0 for append-only buckets where keys are written in ascending order.",
+			Labels: []nu.Label{{Text: "invalid fill percent", Span: v.Span}},
+		}
+	}
+	return fill, nil
+}
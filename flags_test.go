@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+func Test_valueFilter(t *testing.T) {
+	str := func(s string) *nu.Value { return &nu.Value{Value: s} }
+
+	var testCases = []struct {
+		name     string
+		match    *nu.Value
+		contains *nu.Value
+		active   bool
+		pass     []string
+		reject   []string
+	}{
+		{name: "no filter", pass: []string{"", "foo"}},
+		{name: "regexp", match: str("^fo+$"), active: true, pass: []string{"fo", "foo"}, reject: []string{"", "bar", "food"}},
+		{name: "substring", contains: str("oo"), active: true, pass: []string{"foo", "boot"}, reject: []string{"", "fo", "bar"}},
+		{name: "binary substring", contains: &nu.Value{Value: []byte{0, 1}}, active: true, pass: []string{"a\x00\x01b"}, reject: []string{"\x01\x00"}},
+		{name: "both", match: str("^b"), contains: str("oo"), active: true, pass: []string{"boot"}, reject: []string{"foo", "bar"}},
+	}
+	for _, tc := range testCases {
+		filter, active, err := valueFilter(tc.match, tc.contains)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if active != tc.active {
+			t.Errorf("%s: expected active to be %t", tc.name, tc.active)
+		}
+		for _, v := range tc.pass {
+			if !filter([]byte(v)) {
+				t.Errorf("%s: expected %q to pass the filter", tc.name, v)
+			}
+		}
+		for _, v := range tc.reject {
+			if filter([]byte(v)) {
+				t.Errorf("%s: expected %q to be rejected by the filter", tc.name, v)
+			}
+		}
+	}
+
+	t.Run("invalid regexp", func(t *testing.T) {
+		span := nu.Span{Start: 5, End: 9}
+		_, _, err := valueFilter(&nu.Value{Value: "a(b", Span: span}, nil)
+		var nuErr nu.Error
+		if !errors.As(err, &nuErr) {
+			t.Fatalf("expected nu.Error, got %#v", err)
+		}
+		if nuErr.Code != "go::regexp::syntax" || len(nuErr.Labels) != 1 || nuErr.Labels[0].Span != span {
+			t.Errorf("unexpected error: %#v", nuErr)
+		}
+	})
+}
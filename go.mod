@@ -4,11 +4,11 @@ go 1.24.0
 
 require (
 	github.com/ainvaltin/nu-plugin v0.0.0-20260412195652-cb2abbc7c636
+	github.com/vmihailenco/msgpack/v5 v5.4.1
 	go.etcd.io/bbolt v1.4.3
 )
 
 require (
-	github.com/vmihailenco/msgpack/v5 v5.4.1 // indirect
 	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 	golang.org/x/sys v0.41.0 // indirect
 )
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/bits"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+/*
+decodeGob decodes the first value of the gob stream into Nu value without
+knowing the Go types used to encode it - gob streams are self-describing,
+the type definitions are sent before the value. Structs and maps are
+returned as records, slices and arrays as lists.
+*/
+func decodeGob(data []byte) (nu.Value, error) {
+	d := gobDecoder{types: gobBuiltinTypes(), rest: data}
+	for len(d.rest) > 0 {
+		if err := d.nextMessage(); err != nil {
+			return nu.Value{}, err
+		}
+		id, err := d.int()
+		if err != nil {
+			return nu.Value{}, err
+		}
+		if id < 0 {
+			if err := d.defineType(-id); err != nil {
+				return nu.Value{}, err
+			}
+			continue
+		}
+		return d.topLevel(id)
+	}
+	return nu.Value{}, errors.New("gob: stream doesn't contain a value")
+}
+
+type gobKind uint8
+
+const (
+	gobBool gobKind = iota + 1
+	gobInt
+	gobUint
+	gobFloat
+	gobBytes
+	gobString
+	gobComplex
+	gobInterface
+	gobArray
+	gobSlice
+	gobStruct
+	gobMap
+	gobEncoder // GobEncoder, BinaryMarshaler or TextMarshaler
+)
+
+type gobField struct {
+	name string
+	id   int64
+}
+
+type gobType struct {
+	kind   gobKind
+	name   string
+	elem   int64
+	key    int64
+	len    int64
+	fields []gobField
+}
+
+/*
+gobBuiltinTypes returns the predefined types, including the types used to
+describe user types (wireType and it's components).
+*/
+func gobBuiltinTypes() map[int64]*gobType {
+	common := gobField{"CommonType", 18}
+	return map[int64]*gobType{
+		1: {kind: gobBool},
+		2: {kind: gobInt},
+		3: {kind: gobUint},
+		4: {kind: gobFloat},
+		5: {kind: gobBytes},
+		6: {kind: gobString},
+		7: {kind: gobComplex},
+		8: {kind: gobInterface},
+		// wireType
+		16: {kind: gobStruct, fields: []gobField{{"ArrayT", 17}, {"SliceT", 19}, {"StructT", 20}, {"MapT", 23}, {"GobEncoderT", 24}, {"BinaryMarshalerT", 24}, {"TextMarshalerT", 24}}},
+		17: {kind: gobStruct, fields: []gobField{common, {"Elem", 2}, {"Len", 2}}},
+		18: {kind: gobStruct, fields: []gobField{{"Name", 6}, {"Id", 2}}},
+		19: {kind: gobStruct, fields: []gobField{common, {"Elem", 2}}},
+		20: {kind: gobStruct, fields: []gobField{common, {"Field", 22}}},
+		21: {kind: gobStruct, fields: []gobField{{"Name", 6}, {"Id", 2}}},
+		22: {kind: gobSlice, elem: 21},
+		23: {kind: gobStruct, fields: []gobField{common, {"Key", 2}, {"Elem", 2}}},
+		24: {kind: gobStruct, fields: []gobField{common}},
+	}
+}
+
+type gobDecoder struct {
+	b     []byte // current message
+	rest  []byte // messages following the current one
+	types map[int64]*gobType
+	depth int
+}
+
+/*
+nextMessage makes the next message of the stream (byte count followed by
+the content) the current one.
+*/
+func (d *gobDecoder) nextMessage() error {
+	n, rest, err := readGobUint(d.rest)
+	if err != nil {
+		return err
+	}
+	if n > uint64(len(rest)) {
+		return errors.New("gob: message length exceeds input size")
+	}
+	d.b, d.rest = rest[:n], rest[n:]
+	return nil
+}
+
+// maximum nesting of the values, protects against stack overflow on malicious input
+const gobMaxDepth = 100
+
+func (d *gobDecoder) uint() (n uint64, err error) {
+	n, d.b, err = readGobUint(d.b)
+	return n, err
+}
+
+func readGobUint(b []byte) (uint64, []byte, error) {
+	if len(b) == 0 {
+		return 0, b, errors.New("gob: unexpected end of data")
+	}
+	if b[0] < 0x80 {
+		return uint64(b[0]), b[1:], nil
+	}
+	n := -int(int8(b[0]))
+	if n > 8 || n >= len(b) {
+		return 0, b, errors.New("gob: invalid unsigned integer")
+	}
+	var buf [8]byte
+	copy(buf[8-n:], b[1:n+1])
+	return binary.BigEndian.Uint64(buf[:]), b[n+1:], nil
+}
+
+func (d *gobDecoder) int() (int64, error) {
+	u, err := d.uint()
+	if err != nil {
+		return 0, err
+	}
+	if u&1 != 0 {
+		return ^int64(u >> 1), nil
+	}
+	return int64(u >> 1), nil
+}
+
+func (d *gobDecoder) float() (float64, error) {
+	u, err := d.uint()
+	return math.Float64frombits(bits.ReverseBytes64(u)), err
+}
+
+func (d *gobDecoder) bytes() ([]byte, error) {
+	n, err := d.uint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(d.b)) {
+		return nil, errors.New("gob: length exceeds input size")
+	}
+	r := d.b[:n:n]
+	d.b = d.b[n:]
+	return r, nil
+}
+
+/*
+defineType reads the type definition (wireType) and adds it to the known types.
+*/
+func (d *gobDecoder) defineType(id int64) error {
+	v, err := d.decodeStruct(d.types[16])
+	if err != nil {
+		return fmt.Errorf("gob: decoding type definition: %w", err)
+	}
+	wt := v.Value.(nu.Record)
+
+	field := func(r nu.Record, name string) int64 {
+		n, _ := r[name].Value.(int64)
+		return n
+	}
+	name := func(r nu.Record) string {
+		c, _ := r["CommonType"].Value.(nu.Record)
+		s, _ := c["Name"].Value.(string)
+		return s
+	}
+
+	var t *gobType
+	for k, v := range wt {
+		r, ok := v.Value.(nu.Record)
+		if !ok {
+			continue
+		}
+		switch k {
+		case "ArrayT":
+			t = &gobType{kind: gobArray, elem: field(r, "Elem"), len: field(r, "Len")}
+		case "SliceT":
+			t = &gobType{kind: gobSlice, elem: field(r, "Elem")}
+		case "MapT":
+			t = &gobType{kind: gobMap, key: field(r, "Key"), elem: field(r, "Elem")}
+		case "StructT":
+			t = &gobType{kind: gobStruct}
+			fields, _ := r["Field"].Value.([]nu.Value)
+			for _, f := range fields {
+				fr, _ := f.Value.(nu.Record)
+				fname, _ := fr["Name"].Value.(string)
+				t.fields = append(t.fields, gobField{name: fname, id: field(fr, "Id")})
+			}
+		case "GobEncoderT", "BinaryMarshalerT", "TextMarshalerT":
+			t = &gobType{kind: gobEncoder}
+		default:
+			continue
+		}
+		t.name = name(r)
+	}
+	if t == nil {
+		return fmt.Errorf("gob: unsupported type definition for type id %d", id)
+	}
+	d.types[id] = t
+	return nil
+}
+
+/*
+topLevel decodes top-level value, non-struct values are encoded as if they
+were the only field of an unnamed struct (ie preceded by zero field delta).
+*/
+func (d *gobDecoder) topLevel(id int64) (nu.Value, error) {
+	t, ok := d.types[id]
+	if !ok {
+		return nu.Value{}, fmt.Errorf("gob: unknown type id %d", id)
+	}
+	if t.kind == gobStruct {
+		return d.decodeStruct(t)
+	}
+	if _, err := d.uint(); err != nil {
+		return nu.Value{}, err
+	}
+	return d.decode(id)
+}
+
+func (d *gobDecoder) decode(id int64) (v nu.Value, err error) {
+	t, ok := d.types[id]
+	if !ok {
+		return v, fmt.Errorf("gob: unknown type id %d", id)
+	}
+	if d.depth++; d.depth > gobMaxDepth {
+		return v, errors.New("gob: value nested too deeply")
+	}
+	defer func() { d.depth-- }()
+
+	switch t.kind {
+	case gobBool:
+		n, err := d.uint()
+		return nu.Value{Value: n != 0}, err
+	case gobInt:
+		n, err := d.int()
+		return nu.Value{Value: n}, err
+	case gobUint:
+		n, err := d.uint()
+		if err == nil && n > math.MaxInt64 {
+			return v, fmt.Errorf("gob: unsigned integer %d overflows int64", n)
+		}
+		return nu.Value{Value: int64(n)}, err
+	case gobFloat:
+		f, err := d.float()
+		return nu.Value{Value: f}, err
+	case gobComplex:
+		re, err := d.float()
+		if err != nil {
+			return v, err
+		}
+		im, err := d.float()
+		return nu.Value{Value: nu.Record{"real": nu.Value{Value: re}, "imag": nu.Value{Value: im}}}, err
+	case gobBytes:
+		// data might be memory mapped database so do not return sub-slices of it
+		b, err := d.bytes()
+		return nu.Value{Value: slices.Clone(b)}, err
+	case gobString:
+		b, err := d.bytes()
+		return nu.Value{Value: string(b)}, err
+	case gobInterface:
+		return d.decodeInterface()
+	case gobArray, gobSlice:
+		n, err := d.uint()
+		if err != nil {
+			return v, err
+		}
+		if n > uint64(len(d.b)) {
+			return v, errors.New("gob: element count exceeds input size")
+		}
+		items := make([]nu.Value, 0, n)
+		for range n {
+			item, err := d.decode(t.elem)
+			if err != nil {
+				return v, err
+			}
+			items = append(items, item)
+		}
+		return nu.Value{Value: items}, nil
+	case gobMap:
+		n, err := d.uint()
+		if err != nil {
+			return v, err
+		}
+		r := nu.Record{}
+		for range n {
+			k, err := d.decode(t.key)
+			if err != nil {
+				return v, err
+			}
+			item, err := d.decode(t.elem)
+			if err != nil {
+				return v, err
+			}
+			r[recordKey(k.Value)] = item
+		}
+		return nu.Value{Value: r}, nil
+	case gobStruct:
+		return d.decodeStruct(t)
+	case gobEncoder:
+		b, err := d.bytes()
+		if err != nil {
+			return v, err
+		}
+		// gob sends the name of the type without package name, so
+		// "Time" might be something else than time.Time
+		if t.name == "Time" || t.name == "time.Time" {
+			var tm time.Time
+			if err := tm.UnmarshalBinary(b); err == nil {
+				return nu.Value{Value: tm}, nil
+			}
+		}
+		return nu.Value{Value: slices.Clone(b)}, nil
+	default:
+		return v, fmt.Errorf("gob: unsupported type id %d", id)
+	}
+}
+
+/*
+decodeStruct decodes struct as a record. Gob doesn't send fields with zero
+value so these are added with the zero value of the basic types (nothing for
+composite types).
+*/
+func (d *gobDecoder) decodeStruct(t *gobType) (nu.Value, error) {
+	r := make(nu.Record, len(t.fields))
+	for fieldnum := -1; ; {
+		delta, err := d.uint()
+		if err != nil {
+			return nu.Value{}, err
+		}
+		if delta == 0 {
+			break
+		}
+		if delta > uint64(len(t.fields)) || fieldnum+int(delta) >= len(t.fields) {
+			return nu.Value{}, errors.New("gob: invalid field number")
+		}
+		fieldnum += int(delta)
+		f := t.fields[fieldnum]
+		if r[f.name], err = d.decode(f.id); err != nil {
+			return nu.Value{}, fmt.Errorf("field %s: %w", f.name, err)
+		}
+	}
+
+	for _, f := range t.fields {
+		if _, ok := r[f.name]; !ok {
+			r[f.name] = d.zeroValue(f.id)
+		}
+	}
+	return nu.Value{Value: r}, nil
+}
+
+func (d *gobDecoder) zeroValue(id int64) nu.Value {
+	t, ok := d.types[id]
+	if !ok {
+		return nu.Value{}
+	}
+	switch t.kind {
+	case gobBool:
+		return nu.Value{Value: false}
+	case gobInt, gobUint:
+		return nu.Value{Value: int64(0)}
+	case gobFloat:
+		return nu.Value{Value: float64(0)}
+	case gobString:
+		return nu.Value{Value: ""}
+	case gobBytes:
+		return nu.Value{Value: []byte{}}
+	default:
+		return nu.Value{}
+	}
+}
+
+/*
+decodeInterface decodes interface value: name of the concrete type (empty
+for nil), optional type definitions, type id, byte count and the value.
+
+The type definitions of the concrete type (when it wasn't sent before) are
+written by the encoder as separate messages so the value continues in the
+next message when the current one is exhausted.
+*/
+func (d *gobDecoder) decodeInterface() (nu.Value, error) {
+	name, err := d.bytes()
+	if err != nil || len(name) == 0 {
+		return nu.Value{}, err
+	}
+
+	for {
+		if len(d.b) == 0 {
+			if err := d.nextMessage(); err != nil {
+				return nu.Value{}, err
+			}
+		}
+		id, err := d.int()
+		if err != nil {
+			return nu.Value{}, err
+		}
+		if id < 0 {
+			if err := d.defineType(-id); err != nil {
+				return nu.Value{}, err
+			}
+			// when the value follows in the same message skip it's byte
+			// count, otherwise the count is part of the next message header
+			if len(d.b) > 0 {
+				if _, err := d.uint(); err != nil {
+					return nu.Value{}, err
+				}
+			}
+			continue
+		}
+		// byte count of the value
+		if _, err := d.uint(); err != nil {
+			return nu.Value{}, err
+		}
+		return d.topLevel(id)
+	}
+}
+
+/*
+recordKey converts map key into record field name. Composite keys (ie
+structs decoded as records) are formatted like Nu values, ie "{X: 1, Y: 2}".
+*/
+func recordKey(k any) string {
+	switch t := k.(type) {
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	case nu.Value:
+		return recordKey(t.Value)
+	case nu.Record:
+		names := slices.Sorted(maps.Keys(t))
+		items := make([]string, len(names))
+		for i, name := range names {
+			items[i] = name + ": " + recordKey(t[name])
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	case []nu.Value:
+		items := make([]string, len(t))
+		for i, v := range t {
+			items[i] = recordKey(v)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return fmt.Sprint(t)
+	}
+}
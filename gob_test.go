@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+type gobTestInner struct {
+	Name string
+	Tags []string
+}
+
+type gobTestItem struct {
+	ID      uint64
+	Delta   int
+	Ratio   float64
+	Enabled bool
+	Data    []byte
+	Inner   gobTestInner
+	Ptr     *gobTestInner
+	Items   []gobTestInner
+	Attrs   map[string]int
+	Created time.Time
+	Any     any
+	Matrix  [2]int8
+}
+
+// types which are only sent inside interface values
+type gobTestIface struct {
+	X int
+	S string
+}
+
+type gobTestNested struct {
+	N int
+}
+
+type gobTestKey struct {
+	X, Y int
+}
+
+func Test_decodeGob(t *testing.T) {
+	gob.Register(gobTestInner{})
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	encode := func(t *testing.T, v any) []byte {
+		t.Helper()
+		buf := bytes.Buffer{}
+		if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	t.Run("struct", func(t *testing.T) {
+		data := encode(t, gobTestItem{
+			ID:      42,
+			Delta:   -7,
+			Ratio:   0.5,
+			Enabled: true,
+			Data:    []byte{1, 2},
+			Inner:   gobTestInner{Name: "inner", Tags: []string{"a", "b"}},
+			Ptr:     &gobTestInner{Name: "ptr"},
+			Items:   []gobTestInner{{Name: "first"}, {Name: "second"}},
+			Attrs:   map[string]int{"x": 1},
+			Created: created,
+			Any:     gobTestInner{Name: "iface"},
+			Matrix:  [2]int8{3, -4},
+		})
+		v, err := decodeGob(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := v.Value.(nu.Record)
+
+		expected := nu.Record{
+			"ID":      nu.Value{Value: int64(42)},
+			"Delta":   nu.Value{Value: int64(-7)},
+			"Ratio":   nu.Value{Value: 0.5},
+			"Enabled": nu.Value{Value: true},
+			"Data":    nu.Value{Value: []byte{1, 2}},
+			"Inner": nu.Value{Value: nu.Record{
+				"Name": nu.Value{Value: "inner"},
+				"Tags": nu.Value{Value: []nu.Value{{Value: "a"}, {Value: "b"}}},
+			}},
+			"Ptr": nu.Value{Value: nu.Record{"Name": nu.Value{Value: "ptr"}, "Tags": nu.Value{}}},
+			"Items": nu.Value{Value: []nu.Value{
+				{Value: nu.Record{"Name": nu.Value{Value: "first"}, "Tags": nu.Value{}}},
+				{Value: nu.Record{"Name": nu.Value{Value: "second"}, "Tags": nu.Value{}}},
+			}},
+			"Attrs":   nu.Value{Value: nu.Record{"x": nu.Value{Value: int64(1)}}},
+			"Created": nu.Value{Value: created},
+			"Any":     nu.Value{Value: nu.Record{"Name": nu.Value{Value: "iface"}, "Tags": nu.Value{}}},
+			"Matrix":  nu.Value{Value: []nu.Value{{Value: int64(3)}, {Value: int64(-4)}}},
+		}
+		for k, ev := range expected {
+			got := r[k].Value
+			if tm, ok := got.(time.Time); ok && tm.Equal(created) {
+				continue
+			}
+			if !reflect.DeepEqual(got, ev.Value) {
+				t.Errorf("field %s: expected %#v, got %#v", k, ev.Value, got)
+			}
+		}
+	})
+
+	t.Run("zero fields", func(t *testing.T) {
+		v, err := decodeGob(encode(t, gobTestInner{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := v.Value.(nu.Record)
+		if r["Name"].Value != "" || r["Tags"].Value != nil {
+			t.Errorf("unexpected zero values: %#v", r)
+		}
+	})
+
+	t.Run("top-level values", func(t *testing.T) {
+		for _, tc := range []struct {
+			in  any
+			out any
+		}{
+			{in: 42, out: int64(42)},
+			{in: "foo", out: "foo"},
+			{in: []int{1, 2}, out: []nu.Value{{Value: int64(1)}, {Value: int64(2)}}},
+			{in: map[int]string{1: "one"}, out: nu.Record{"1": nu.Value{Value: "one"}}},
+		} {
+			v, err := decodeGob(encode(t, tc.in))
+			if err != nil {
+				t.Errorf("decoding %v: %v", tc.in, err)
+				continue
+			}
+			if !reflect.DeepEqual(v.Value, tc.out) {
+				t.Errorf("expected %#v, got %#v", tc.out, v.Value)
+			}
+		}
+	})
+
+	t.Run("type first defined inside interface", func(t *testing.T) {
+		gob.Register(gobTestIface{})
+		gob.Register(gobTestNested{})
+		gob.Register([]any{})
+		iface := nu.Record{"X": nu.Value{Value: int64(1)}, "S": nu.Value{Value: "a"}}
+		for _, tc := range []struct {
+			name string
+			in   any
+			out  any
+		}{
+			{name: "struct field", in: struct{ Any any }{Any: gobTestIface{X: 1, S: "a"}}, out: nu.Record{"Any": nu.Value{Value: iface}}},
+			{name: "map", in: map[string]any{"k": gobTestIface{X: 1, S: "a"}}, out: nu.Record{"k": nu.Value{Value: iface}}},
+			{name: "slice", in: []any{gobTestIface{X: 1, S: "a"}, gobTestIface{X: 1, S: "a"}}, out: []nu.Value{{Value: iface}, {Value: iface}}},
+			{name: "nested interface", in: []any{[]any{gobTestNested{N: 2}}}, out: []nu.Value{{Value: []nu.Value{{Value: nu.Record{"N": nu.Value{Value: int64(2)}}}}}}},
+		} {
+			v, err := decodeGob(encode(t, tc.in))
+			if err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+				continue
+			}
+			if !reflect.DeepEqual(v.Value, tc.out) {
+				t.Errorf("%s: expected %#v, got %#v", tc.name, tc.out, v.Value)
+			}
+		}
+	})
+
+	t.Run("unsigned integer out of range", func(t *testing.T) {
+		if v, err := decodeGob(encode(t, uint64(math.MaxUint64))); err == nil {
+			t.Errorf("expected error, got %#v", v.Value)
+		}
+	})
+
+	t.Run("struct map key", func(t *testing.T) {
+		v, err := decodeGob(encode(t, map[gobTestKey]int{{X: 1, Y: 2}: 3}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := (nu.Record{"{X: 1, Y: 2}": nu.Value{Value: int64(3)}}); !reflect.DeepEqual(v.Value, exp) {
+			t.Errorf("expected %#v, got %#v", exp, v.Value)
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		data := encode(t, gobTestItem{ID: 1, Inner: gobTestInner{Name: "inner"}})
+		for i := range data {
+			// must not panic
+			decodeGob(data[:i])
+		}
+	})
+}
@@ -20,10 +20,15 @@ func listBuckets(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error
 		return err
 	}
 
+	rng, err := getRange(call)
+	if err != nil {
+		return err
+	}
+
 	format := getFormatter(call)
 
 	return db.View(func(tx *bbolt.Tx) error {
-		b, err := goToBucket(tx, path)
+		buckets, err := findBuckets(tx, path)
 		if err != nil {
 			return err
 		}
@@ -34,12 +39,20 @@ func listBuckets(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error
 		}
 		defer close(out)
 
-		return b.ForEachBucket(func(k []byte) error {
-			if filter(k) {
-				out <- format(k)
+		for _, m := range buckets {
+			tag := m.tagger(format)
+			var last []byte
+			truncated, err := rng.forEach(m.bucket.Cursor(), bucketsOnly(filter), func(k, v []byte) error {
+				out <- tag(format(k), "name")
+				last = k
+				return nil
+			})
+			if err != nil {
+				return err
 			}
-			return nil
-		})
+			rng.sendToken(out, truncated, last, tag)
+		}
+		return nil
 	})
 }
 
@@ -54,10 +67,20 @@ func listKeys(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
 		return err
 	}
 
+	valueFilter, _, err := getValueFilter(call)
+	if err != nil {
+		return err
+	}
+
+	rng, err := getRange(call)
+	if err != nil {
+		return err
+	}
+
 	format := getFormatter(call)
 
 	return db.View(func(tx *bbolt.Tx) error {
-		b, err := goToBucket(tx, path)
+		buckets, err := findBuckets(tx, path)
 		if err != nil {
 			return err
 		}
@@ -68,11 +91,19 @@ func listKeys(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
 		}
 		defer close(out)
 
-		return b.ForEach(func(k, v []byte) error {
-			if v != nil && filter(k) {
-				out <- format(k)
+		for _, m := range buckets {
+			tag := m.tagger(format)
+			var last []byte
+			truncated, err := rng.forEach(m.bucket.Cursor(), keysOnly(filter, valueFilter), func(k, v []byte) error {
+				out <- tag(format(k), "key")
+				last = k
+				return nil
+			})
+			if err != nil {
+				return err
 			}
-			return nil
-		})
+			rng.sendToken(out, truncated, last, tag)
+		}
+		return nil
 	})
 }
@@ -34,7 +34,8 @@ func main() {
 }
 
 func boltCmd() *nu.Command {
-	nameShape := syntaxshape.OneOf(syntaxshape.List(syntaxshape.Any()), syntaxshape.Binary(), syntaxshape.String())
+	// Int and DateTime are for the key codecs (literals are parsed as String as it comes first)
+	nameShape := syntaxshape.OneOf(syntaxshape.List(syntaxshape.Any()), syntaxshape.Binary(), syntaxshape.String(), syntaxshape.Int(), syntaxshape.DateTime())
 	cmd := &nu.Command{
 		Signature: nu.PluginSignature{
 			Name:        "boltdb",
@@ -46,17 +47,81 @@ func boltCmd() *nu.Command {
 				{In: types.Nothing(), Out: types.Any()},
 				{In: types.Binary(), Out: types.Any()},
 				{In: types.String(), Out: types.Any()},
+				{In: types.List(types.Any()), Out: types.Any()},
 			},
 			Named: []nu.Flag{
 				{Long: "bucket", Short: 'b', Shape: nameShape, Desc: "Name of the bucket to operate on. Nested buckets are represented by " +
-					"list, ie path `foo -> bar` would be [foo, bar]. Nested lists can be used to build bucket name from parts. When not provided action takes place in the root bucket."},
+					"list, ie path `foo -> bar` would be [foo, bar]. Nested lists can be used to build bucket name from parts. When not provided action takes place in the root bucket. " +
+					"Path items \"*\" and {match: <regex>} are wildcards matching multiple sibling buckets (actions buckets, keys, get, count and delete)."},
 				{Long: "key", Short: 'k', Shape: nameShape, Desc: `Name of the key to operate on. If the value is List all items will be concatenated to single byte array, ie given '-k ["item " 0x[0005]]' the key name used would be string "item" followed by space and two bytes with values 0 and 5, it's equivalent to '-k 0x[6974656D200005]'.`},
+				{Long: "keys", Shape: syntaxshape.List(nameShape), Desc: "List of keys to fetch with the \"get\" action, alternatively the list can be given as input. Items accept the same values as the \"key\" flag."},
 				{Long: "match", Short: 'r', Shape: syntaxshape.String(), Desc: "Regex to filter keys or buckets by name - if the name matches the regex it is included in the output."},
+				{Long: "match-value", Shape: syntaxshape.String(), Desc: "Regex to filter keys by value - only keys whose value matches the regex are included (actions keys, get and delete)."},
+				{Long: "value-contains", Shape: nameShape, Desc: "Only keys whose value contains given byte sequence are included (actions keys, get and delete). Accepts the same values as the \"key\" flag."},
+				{Long: "prefix", Short: 'p', Shape: nameShape, Desc: "Only keys or buckets whose name starts with given prefix are included. Accepts the same values as the \"key\" flag, ie binary prefix can be given as '-p [user 0x[00]]'."},
+				{Long: "reverse", Desc: "Iterate in descending order, ie start from the last key."},
+				{Long: "skip", Shape: syntaxshape.Int(), Desc: "Number of the matching keys or buckets to skip before starting to return results."},
+				{Long: "limit", Short: 'n', Shape: syntaxshape.Int(), Desc: "Maximum number of keys or buckets to return (must be greater than zero), the database is not read past the last returned item."},
+				{Long: "after", Shape: nameShape, Desc: "Resume iteration after given key (the key itself is not included), in reverse mode keys before the given key are returned."},
+				{Long: "continuation", Desc: "When the output is truncated by the \"limit\" flag record {after: <key>} is sent as the last item of the output. The key can be used as value of the \"after\" flag to fetch the next page."},
+				{Long: "max-depth", Shape: syntaxshape.Int(), Desc: "Maximum depth of the nested buckets the \"walk\" action descends into, 1 means only the direct children of the bucket are returned."},
+				{Long: "values", Desc: "Include values of the keys in the output of the \"walk\" action."},
+				{Long: "recursive", Desc: "Include the content of the nested buckets in the output of the \"count\" action, action \"clear\" deletes the nested buckets too."},
+				{Long: "reset-sequence", Desc: "Action \"clear\" sets the sequence of the bucket to zero (by default the sequence is kept)."},
+				{Long: "create", Desc: "Action \"set\" creates the buckets of the path which do not exist yet (in the same transaction the value is written)."},
+				{Long: "if-absent", Desc: "Action \"set\" writes the value only when the key doesn't exist yet."},
+				{Long: "if-equals", Shape: nameShape, Desc: "Action \"set\" writes the value only when the current value of the key is equal to given value (compare-and-swap). Accepts the same values as the \"data\" argument."},
+				{Long: "dest", Shape: nameShape, Desc: "Destination of the \"move\", \"copy\" and \"rename\" actions. When moving bucket it's the new path of the bucket, when moving key (\"key\" flag is given) it's the path of the target bucket the key is moved into. For \"copy\" it's the path of the destination bucket and for \"rename\" the new name of the key or bucket."},
+				{Long: "dest-key", Shape: nameShape, Desc: "New name of the key moved by the \"move\" action, by default the key keeps it's name."},
+				{Long: "dest-db", Shape: syntaxshape.Filepath(), Desc: "Name of the destination database file of the \"copy\" action, created if it doesn't exist."},
+				{Long: "overwrite", Desc: "Action \"copy\" overwrites the keys which already exist in the destination bucket, by default existing keys are kept."},
+				{Long: "set", Shape: syntaxshape.Int(), Desc: "Action \"sequence\" sets the sequence of the bucket to given value."},
+				{Long: "next", Desc: "Action \"sequence\" increments the sequence of the bucket and returns the new value."},
+				{Long: "backup", Shape: syntaxshape.String(), Desc: "Name of the backup file to restore with the \"restore\" action (by default the latest backup of the database is restored)."},
+				{Long: "force", Desc: "Restore the backup even when the current database can't be opened (ie it's corrupt), the current database is not locked nor backed up before it's replaced (\"restore\" action)."},
+				{Long: "fill-percent", Shape: syntaxshape.Number(), Desc: "Fill percent (0.1 ... 1.0) of the buckets written by the \"set\" action, bbolt's default is 0.5. Use 1.0 when keys are appended in ascending order to get fully packed pages."},
+				{Long: "presort", Desc: "Action \"set\" sorts the input records by bucket and key before writing them (all the records are read into memory first)."},
+				{Long: "no-sync", Desc: "Action \"set\" doesn't fsync the database after every commit, the database is synced once after all the records are written. Database might get corrupted when the system crashes during the load."},
+				{Long: "commit-every", Shape: syntaxshape.Int(), Desc: "When writing list of records with the \"set\" action commit the transaction after every N records (by default all the records are written in a single transaction)."},
+				{Long: "dry-run", Desc: "Do not modify the database, the changes are made in a transaction which is rolled back and summary of the changes is returned instead of the action's result (actions which modify the database)."},
+				{Long: "from", Shape: nameShape, Desc: "Start of the key range (inclusive), iteration starts from the first key which is equal to or greater than the value. Accepts the same values as the \"key\" flag."},
+				{Long: "to", Shape: nameShape, Desc: "End of the key range (exclusive), iteration stops at the first key which is equal to or greater than the value."},
+				{Long: "through", Shape: nameShape, Desc: "End of the key range (inclusive), iteration stops at the first key which is greater than the value."},
+				{
+					Long:  "value-format",
+					Shape: syntaxshape.String(),
+					Desc:  "Decode values returned by the \"get\" action, values: binary, json, msgpack, gob, text, hex, stringify. Values which can't be decoded are returned as binary with error message in the \"error\" column.",
+					Completions: nu.DynamicCompletion(func() []nu.DynamicSuggestion {
+						return []nu.DynamicSuggestion{
+							{Value: "binary", Description: "raw value (default)"},
+							{Value: "json", Description: "decode JSON document"},
+							{Value: "msgpack", Description: "decode MessagePack value"},
+							{Value: "gob", Description: "decode Go gob stream (without knowing the Go types)"},
+							{Value: "text", Description: "UTF-8 text"},
+							{Value: "hex", Description: "hexadecimal string representation of the value"},
+							{Value: "stringify", Description: "as much human readable as possible"},
+						}
+					}),
+				},
+				{
+					Long:  "key-codec",
+					Shape: syntaxshape.String(),
+					Desc:  "Encode the values of the \"key\", \"keys\", \"dest-key\", \"prefix\" and key range flags (and of the \"key\" field of the records of the \"set\" and \"tx\" actions) using given codec, values: u64be, i64be, unixnano, uuid, ulid. Ie '-k 42 --key-codec u64be' addresses the 8 byte key, Binary values are used as is.",
+					Completions: nu.DynamicCompletion(func() []nu.DynamicSuggestion {
+						return []nu.DynamicSuggestion{
+							{Value: "u64be", Description: "unsigned 64 bit big-endian integer (ie IDs from the bucket sequence)"},
+							{Value: "i64be", Description: "signed 64 bit big-endian integer"},
+							{Value: "unixnano", Description: "date as 64 bit big-endian integer of nanoseconds since Unix epoch"},
+							{Value: "uuid", Description: "16 byte UUID, given as string"},
+							{Value: "ulid", Description: "16 byte ULID, given as string"},
+						}
+					}),
+				},
 				{
 					Long:  "format",
 					Short: 'f',
 					Shape: syntaxshape.String(),
-					Desc:  "Format key/bucket names (commands `buckets` and `keys`), values: binary, hex, text, stringify",
+					Desc:  "Format key/bucket names (commands `buckets`, `keys`, `get`, `walk` and `count`), values: binary, hex, HEX, text, stringify, u64be, i64be, unixnano, uuid, ulid. Names which are not valid for the key codec (ie have wrong length) are returned as binary.",
 					Completions: nu.DynamicCompletion(func() []nu.DynamicSuggestion {
 						return []nu.DynamicSuggestion{
 							{Value: "binary", Description: "native format (shows up as list of integers)"},
@@ -64,6 +129,11 @@ func boltCmd() *nu.Command {
 							{Value: "HEX", Description: "hexadecimal string representation of the binary (upper case)"},
 							{Value: "text", Description: "if possible use text instead of binary, usable as input for b or k flag"},
 							{Value: "stringify", Description: "as much human readable as possible"},
+							{Value: "u64be", Description: "8 byte names as unsigned big-endian integer"},
+							{Value: "i64be", Description: "8 byte names as signed big-endian integer"},
+							{Value: "unixnano", Description: "8 byte names as date (big-endian nanoseconds since Unix epoch)"},
+							{Value: "uuid", Description: "16 byte names as UUID string"},
+							{Value: "ulid", Description: "16 byte names as ULID string"},
 						}
 					}),
 				},
@@ -73,7 +143,7 @@ func boltCmd() *nu.Command {
 				{
 					Name:  "action",
 					Shape: syntaxshape.String(),
-					Desc:  "Operation to perform: buckets, keys, get, set, add, delete, stat, info",
+					Desc:  "Operation to perform: buckets, keys, get, set, add, delete, stat, info, walk, count, exists, tx, move, rename, copy, sequence, clear, restore",
 					Completions: nu.DynamicCompletion(func() []nu.DynamicSuggestion {
 						return []nu.DynamicSuggestion{
 							{Value: "buckets", Description: "list buckets"},
@@ -84,6 +154,16 @@ func boltCmd() *nu.Command {
 							{Value: "delete", Description: "delete key or bucket"},
 							{Value: "stat", Description: "return statistics on a bucket"},
 							{Value: "info", Description: "returns the structure of the bucket"},
+							{Value: "walk", Description: "recursively list all the nested buckets and keys of the bucket"},
+							{Value: "count", Description: "count keys and nested buckets of the bucket"},
+							{Value: "exists", Description: "check does the bucket or key exist"},
+							{Value: "tx", Description: "apply list of operations in a single transaction"},
+							{Value: "move", Description: "move key or bucket to another bucket"},
+							{Value: "rename", Description: "rename key or bucket"},
+							{Value: "copy", Description: "copy bucket into another database"},
+							{Value: "sequence", Description: "get or set the sequence of the bucket"},
+							{Value: "clear", Description: "delete all the keys of the bucket"},
+							{Value: "restore", Description: "replace the database with a backup"},
 						}
 					}),
 				},
@@ -96,7 +176,36 @@ func boltCmd() *nu.Command {
 			{Description: `List buckets in the bucket "foo"`, Example: `boltdb /db/file.name buckets -b foo`, Result: &nu.Value{Value: []nu.Value{{Value: []byte("bar")}, {Value: []byte("zoo")}}}},
 			{Description: `Save file content to a key "file.name" in the bucket "files" (read data from input)`, Example: `open /data/file.name --raw | boltdb /db/file.name set -b files -k file.name`},
 			{Description: `Set key "buz" in nested bucket "foo -> bar" (read data from argument)`, Example: `boltdb /db/file.name set -b [foo, bar] -k buz 0x[010203]`},
+			{Description: `Set key "buz" in the bucket "foo -> bar", creating the buckets if they do not exist`, Example: `boltdb /db/file.name set -b [foo, bar] -k buz --create 0x[010203]`},
+			{Description: `Write key/value pairs from a table into the bucket "users" in a single transaction`, Example: `[[key value]; [alice 0x[01]] [bob 0x[02]]] | boltdb /db/file.name set -b users`},
+			{Description: `Bulk load log records into append-only bucket "log" with fully packed pages`, Example: `open log.json | each {|r| {key: ($r.id | fill -a r -w 10 -c 0), value: ($r | to json)} } | boltdb /db/file.name set -b log --create --presort --fill-percent 1.0 --no-sync`},
+			{Description: `Set two keys, delete one and create a bucket atomically`, Example: `[{op: set, bucket: users, key: alice, value: 0x[01]}, {op: set, bucket: users, key: bob, value: 0x[02]}, {op: delete, bucket: users, key: carol}, {op: add, bucket: [users, archive]}] | boltdb /db/file.name tx`},
+			{Description: `Update the key "counter" only if it's current value is 0x[01]`, Example: `boltdb /db/file.name set -b stats -k counter --if-equals 0x[01] 0x[02]`, Result: &nu.Value{Value: nu.Record{"written": nu.Value{Value: true}, "previous": nu.Value{Value: []byte{1}}}}},
+			{Description: `Rename bucket "foo -> bar" to "foo -> baz"`, Example: `boltdb /db/file.name rename -b [foo, bar] --dest baz`},
+			{Description: `Move bucket "foo -> bar" (with all it's nested buckets) to "archive -> 2024 -> bar"`, Example: `boltdb /db/file.name move -b [foo, bar] --dest [archive, 2024, bar]`},
+			{Description: `Copy bucket "users" into the test database (existing keys are overwritten)`, Example: `boltdb /db/prod.db copy -b users --dest-db /db/test.db --overwrite`, Result: &nu.Value{Value: nu.Record{"keys": nu.Value{Value: 120}, "buckets": nu.Value{Value: 2}, "skipped": nu.Value{Value: 0}}}},
+			{Description: `Allocate the next ID from the sequence of the bucket "users"`, Example: `boltdb /db/file.name sequence -b users --next`, Result: &nu.Value{Value: 43}},
+			{Description: `Delete all the keys and nested buckets of the bucket "cache"`, Example: `boltdb /db/file.name clear -b cache --recursive`, Result: &nu.Value{Value: nu.Record{"keys": nu.Value{Value: 42}, "buckets": nu.Value{Value: 2}, "bytes": nu.Value{Value: nu.Filesize(1024)}}}},
+			{Description: `Restore the latest backup of the database (requires "backupDir" configuration option)`, Example: `boltdb /db/file.name restore`},
 			{Description: `List keys starting with "bl" (byte values 0x62 and 0x6c)`, Example: `boltdb /db/file.name keys -r ^bl.*`, Result: &nu.Value{Value: []nu.Value{{Value: []byte{0x62, 0x6c, 111, 99, 107}}}}},
+			{Description: `List keys starting with "user" followed by zero byte`, Example: `boltdb /db/file.name keys -b users -p [user 0x[00]]`},
+			{Description: `Get the last 20 entries of the bucket "log"`, Example: `boltdb /db/file.name get -b log --reverse --limit 20`},
+			{Description: `List the whole hierarchy under the bucket "tenants", names formatted as text`, Example: `boltdb /db/file.name walk -b tenants -f text`},
+			{Description: `Count keys starting with "user" in the bucket "users"`, Example: `boltdb /db/file.name count -b users -p user`, Result: &nu.Value{Value: nu.Record{"keys": nu.Value{Value: 42}, "buckets": nu.Value{Value: 0}, "bytes": nu.Value{Value: nu.Filesize(1024)}}}},
+			{Description: `Check does the key "foo" exist in the bucket "bar"`, Example: `if (boltdb /db/file.name exists -b bar -k foo) { print "found" }`},
+			{Description: `List keys of the "sessions" bucket of every tenant`, Example: `boltdb /db/file.name keys -b [tenants, *, sessions]`},
+			{Description: `Find the records referencing ID "0042"`, Example: `boltdb /db/file.name get -b orders --value-contains 0042`},
+			{Description: `Fetch multiple keys from the bucket "users" in a single transaction`, Example: `[alice bob carol] | boltdb /db/file.name get -b users -f text`, Result: &nu.Value{Value: []nu.Value{
+				{Value: nu.Record{"key": nu.Value{Value: "alice"}, "value": nu.Value{Value: []byte{1}}, "found": nu.Value{Value: true}}},
+				{Value: nu.Record{"key": nu.Value{Value: "bob"}, "value": nu.Value{}, "found": nu.Value{Value: false}}},
+				{Value: nu.Record{"key": nu.Value{Value: "carol"}, "value": nu.Value{Value: []byte{3}}, "found": nu.Value{Value: true}}},
+			}}},
+			{Description: `Preview which keys of the bucket "cache" would be deleted by the regex`, Example: `boltdb /db/file.name delete -b cache -r ^tmp- --dry-run -f text | get deleted`},
+			{Description: `Get the next page of 1000 keys after the key "foo"`, Example: `boltdb /db/file.name keys -b log --after foo --limit 1000 --continuation`},
+			{Description: `Get the users stored as JSON documents as table of records`, Example: `boltdb /db/file.name get -b users -p user: --value-format json -f text`},
+			{Description: `Get the value of the key with ID 42 (8 byte big-endian integer) from the bucket "orders"`, Example: `boltdb /db/file.name get -b orders -k 42 --key-codec u64be`},
+			{Description: `List the IDs of the events logged since given date (keys are big-endian Unix nanoseconds)`, Example: `boltdb /db/file.name keys -b events --from 2024-06-01 --key-codec unixnano -f unixnano`},
+			{Description: `Get key/value pairs of the keys from "2024-01" up to (but not including) "2024-02"`, Example: `boltdb /db/file.name get -b events --from 2024-01 --to 2024-02`},
 		},
 		OnRun: boltCmdHandler,
 	}
@@ -109,6 +218,14 @@ func boltCmdHandler(ctx context.Context, call *nu.ExecCommand) error {
 		return fmt.Errorf("invalid arguments: %w", err)
 	}
 
+	// these actions open the database themselves
+	switch action {
+	case "restore":
+		return restore(ctx, call)
+	case "exists":
+		return exists(ctx, call)
+	}
+
 	db, err := openDB(ctx, call, action)
 	if err != nil {
 		return err
@@ -123,6 +240,9 @@ func boltCmdHandler(ctx context.Context, call *nu.ExecCommand) error {
 	case "get":
 		return getValue(ctx, db, call)
 	case "set":
+		if _, key := call.FlagValue("key"); !key {
+			return setValues(ctx, db, call)
+		}
 		return setValue(ctx, db, call)
 	case "add":
 		return addBucket(ctx, db, call)
@@ -132,6 +252,20 @@ func boltCmdHandler(ctx context.Context, call *nu.ExecCommand) error {
 		return stat(ctx, db, call)
 	case "info":
 		return info(ctx, db, call)
+	case "walk":
+		return walk(ctx, db, call)
+	case "count":
+		return count(ctx, db, call)
+	case "tx":
+		return applyOps(ctx, db, call)
+	case "move", "rename":
+		return move(ctx, db, call, action)
+	case "copy":
+		return copyToDB(ctx, db, call)
+	case "sequence":
+		return sequence(ctx, db, call)
+	case "clear":
+		return truncate(ctx, db, call)
 	default:
 		// should actually never end up here, the checkArgs will return error
 		return fmt.Errorf("unknown action %q", action)
@@ -143,27 +277,52 @@ func checkArgs(call *nu.ExecCommand) (action string, err error) {
 	rexValue, filter := call.FlagValue("match")
 	keyValue, key := call.FlagValue("key")
 	_, bucket := call.FlagValue("bucket")
+	keysValue, keys := call.FlagValue("keys")
+	_, from := call.FlagValue("from")
+	toValue, to := call.FlagValue("to")
+	thruValue, through := call.FlagValue("through")
+	prefixValue, prefix := call.FlagValue("prefix")
+	_, reverse := call.FlagValue("reverse")
+	_, skip := call.FlagValue("skip")
+	_, limit := call.FlagValue("limit")
+	_, after := call.FlagValue("after")
+	_, matchValue := call.FlagValue("match-value")
+	_, valueContains := call.FlagValue("value-contains")
 
 	action = call.Positional[1].Value.(string)
-	if !slices.Contains([]string{"keys", "get", "set", "add", "delete", "buckets", "stat", "info"}, action) {
+	if !slices.Contains([]string{"keys", "get", "set", "add", "delete", "buckets", "stat", "info", "walk", "count", "exists", "tx", "move", "rename", "copy", "sequence", "clear", "restore"}, action) {
 		return "", nu.Error{
 			Err:    fmt.Errorf("unknown action %q", action),
-			Help:   `valid actions are: "keys", "get", "set", "add", "delete", "buckets", "stat", "info"`,
+			Help:   `valid actions are: "keys", "get", "set", "add", "delete", "buckets", "stat", "info", "walk", "count", "exists", "tx", "move", "rename", "copy", "sequence", "clear", "restore"`,
 			Labels: []nu.Label{{Text: "unknown action", Span: call.Positional[1].Span}},
 		}
 	}
 
 	// do we have required flags set
-	if !bucket && slices.Contains([]string{"add", "get", "keys", "set", "delete"}, action) {
+	// "set" without "key" is batch mode where records might contain bucket
+	if !bucket && (slices.Contains([]string{"add", "get", "keys", "delete", "move", "rename", "copy", "sequence", "clear"}, action) || (action == "set" && key)) {
 		return "", fmt.Errorf(`action %q requires "bucket" flag to be provided`, action)
 	}
-	if !key && action == "set" {
-		return "", fmt.Errorf(`action %q requires "key" flag to be provided`, action)
+	if _, dest := call.FlagValue("dest"); !dest && slices.Contains([]string{"move", "rename"}, action) {
+		return "", fmt.Errorf(`action %q requires "dest" flag to be provided`, action)
+	}
+	if v, destKey := call.FlagValue("dest-key"); destKey && !key && action == "move" {
+		return "", nu.Error{
+			Err:    errors.New(`flag "dest-key" requires the "key" flag, it is the new name of the key being moved`),
+			Labels: []nu.Label{{Text: "requires key", Span: v.Span}},
+		}
+	}
+	if _, destDB := call.FlagValue("dest-db"); !destDB && action == "copy" {
+		return "", fmt.Errorf(`action %q requires "dest-db" flag to be provided`, action)
+	}
+	if !key && action == "set" && (call.Input == nil || len(call.Positional) == 3) {
+		return "", fmt.Errorf(`action %q requires "key" flag to be provided (or list of records as input)`, action)
 	}
 
 	// combinations of flags - either one must be given or only one of the flag can be given
-	if !(key || filter) && slices.Contains([]string{"get", "delete"}, action) {
-		return "", fmt.Errorf(`action %q requires either "key" or "match" flag to be provided`, action)
+	// "delete" without key selection flags deletes the bucket
+	if !(key || keys || filter || prefix || from || to || through || after || reverse || skip || limit || matchValue || valueContains) && call.Input == nil && action == "get" {
+		return "", fmt.Errorf(`action %q requires either "key" or key selection ("match", "prefix", key range or paging) flags to be provided`, action)
 	}
 	// do not allow key and filter at the same time
 	if (key && filter) && slices.Contains([]string{"get", "delete"}, action) {
@@ -173,30 +332,153 @@ func checkArgs(call *nu.ExecCommand) (action string, err error) {
 		}
 	}
 
+	_, ifAbsent := call.FlagValue("if-absent")
+	ifEqualsValue, ifEquals := call.FlagValue("if-equals")
+	if ifAbsent && ifEquals {
+		return "", nu.Error{
+			Err:    errors.New(`only one of the "if-absent" and "if-equals" flags can be used at the same time`),
+			Labels: []nu.Label{{Text: "choose one", Span: call.Named["if-absent"].Span}, {Text: "choose one", Span: ifEqualsValue.Span}},
+		}
+	}
+	if _, presort := call.FlagValue("presort"); presort && key {
+		return "", nu.Error{
+			Err:    errors.New(`flag "presort" requires list of records as input, it can't be combined with the "key" flag`),
+			Labels: []nu.Label{{Text: "presort needs list of records", Span: call.Named["presort"].Span}},
+		}
+	}
+	if (ifAbsent || ifEquals) && !key {
+		return "", errors.New(`conditional write flags require "key" flag to be provided`)
+	}
+	if _, next := call.FlagValue("next"); next {
+		if seq, set := call.FlagValue("set"); set {
+			return "", nu.Error{
+				Err:    errors.New(`only one of the "set" and "next" flags can be used at the same time`),
+				Labels: []nu.Label{{Text: "choose one", Span: seq.Span}, {Text: "choose one", Span: call.Named["next"].Span}},
+			}
+		}
+	}
+	if to && through {
+		return "", nu.Error{
+			Err:    errors.New(`only one of the "to" and "through" flags can be used at the same time`),
+			Labels: []nu.Label{{Text: "choose one", Span: toValue.Span}, {Text: "choose one", Span: thruValue.Span}},
+		}
+	}
+	if keys || (action == "get" && call.Input != nil) {
+		if key || filter || prefix || from || to || through || after || reverse || skip || limit {
+			return "", errors.New(`list of keys (given by "keys" flag or as input) can't be combined with other key selection flags`)
+		}
+	}
+	if key && prefix {
+		return "", nu.Error{
+			Err:    fmt.Errorf(`action %q allows either "key" or "prefix" flag but not both at the same time`, action),
+			Labels: []nu.Label{{Text: "choose one", Span: keyValue.Span}, {Text: "choose one", Span: prefixValue.Span}},
+		}
+	}
+	if key && (from || to || through || after || reverse || skip || limit) {
+		return "", nu.Error{
+			Err:    errors.New(`key range and paging flags can't be combined with the "key" flag`),
+			Labels: []nu.Label{{Text: "single key is selected by the key flag", Span: keyValue.Span}},
+		}
+	}
+
 	// do we have flags set which do not apply for the action
-	if key && !slices.Contains([]string{"get", "set", "delete"}, action) {
+	if key && !slices.Contains([]string{"get", "set", "delete", "exists", "move", "rename"}, action) {
 		return "", flagNotSupportedErr("key", action, keyValue.Span)
 	}
-	if filter && !slices.Contains([]string{"buckets", "keys", "get"}, action) {
+	if filter && !slices.Contains([]string{"buckets", "keys", "get", "count", "delete"}, action) {
 		return "", flagNotSupportedErr("match", action, rexValue.Span)
 	}
+	for _, f := range []struct {
+		name    string
+		actions []string
+	}{
+		{"keys", []string{"get"}},
+		{"commit-every", []string{"set"}},
+		{"fill-percent", []string{"set"}},
+		{"presort", []string{"set"}},
+		{"no-sync", []string{"set"}},
+		{"dest", []string{"move", "rename", "copy"}},
+		{"dest-db", []string{"copy"}},
+		{"set", []string{"sequence"}},
+		{"next", []string{"sequence"}},
+		{"overwrite", []string{"copy"}},
+		{"dest-key", []string{"move"}},
+		{"create", []string{"set"}},
+		{"if-absent", []string{"set"}},
+		{"if-equals", []string{"set"}},
+		{"match-value", []string{"keys", "get", "delete"}},
+		{"value-contains", []string{"keys", "get", "delete"}},
+		{"prefix", []string{"buckets", "keys", "get", "delete", "count"}},
+		{"from", []string{"keys", "get", "count", "delete"}},
+		{"to", []string{"keys", "get", "count", "delete"}},
+		{"through", []string{"keys", "get", "count", "delete"}},
+		{"dry-run", []string{"set", "add", "delete", "tx", "move", "rename", "copy", "clear", "sequence"}},
+		{"after", []string{"buckets", "keys", "get"}},
+		{"continuation", []string{"buckets", "keys", "get"}},
+		{"value-format", []string{"get"}},
+		{"key-codec", []string{"keys", "get", "set", "tx", "delete", "exists", "count", "move", "rename"}},
+		{"max-depth", []string{"walk"}},
+		{"values", []string{"walk"}},
+		{"recursive", []string{"count", "clear"}},
+		{"reset-sequence", []string{"clear"}},
+		{"backup", []string{"restore"}},
+		{"force", []string{"restore"}},
+		{"reverse", []string{"buckets", "keys", "get"}},
+		{"skip", []string{"buckets", "keys", "get"}},
+		{"limit", []string{"buckets", "keys", "get"}},
+	} {
+		if _, ok := call.FlagValue(f.name); ok && !slices.Contains(f.actions, action) {
+			// use Named as FlagValue doesn't return span of the toggle flags
+			return "", flagNotSupportedErr(f.name, action, call.Named[f.name].Span)
+		}
+	}
 	if format {
-		if !slices.Contains([]string{"buckets", "keys", "get"}, action) {
+		// with dry-run the names in the summary are formatted
+		if _, dryRun := call.FlagValue("dry-run"); !dryRun && !slices.Contains([]string{"buckets", "keys", "get", "walk", "count", "delete"}, action) {
 			return "", flagNotSupportedErr("format", action, fmtValue.Span)
 		}
-		if s := fmtValue.Value.(string); !slices.Contains([]string{"binary", "hex", "HEX", "stringify", "text"}, s) {
+		if s := fmtValue.Value.(string); !slices.Contains([]string{"binary", "hex", "HEX", "stringify", "text", "u64be", "i64be", "unixnano", "uuid", "ulid"}, s) {
 			return "", nu.Error{
 				Err:    fmt.Errorf("unsupported format %q", s),
-				Help:   `Valid formats are: "binary", "hex", "HEX", "stringify", "text"`,
+				Help:   `Valid formats are: "binary", "hex", "HEX", "stringify", "text", "u64be", "i64be", "unixnano", "uuid", "ulid"`,
 				Labels: []nu.Label{{Text: "unsupported format specifier", Span: fmtValue.Span}},
 			}
 		}
 	}
 
+	if v, ok := call.FlagValue("value-format"); ok {
+		if s := v.Value.(string); !slices.Contains([]string{"binary", "json", "msgpack", "gob", "text", "hex", "stringify"}, s) {
+			return "", nu.Error{
+				Err:    fmt.Errorf("unsupported value format %q", s),
+				Help:   `Valid value formats are: "binary", "json", "msgpack", "gob", "text", "hex", "stringify"`,
+				Labels: []nu.Label{{Text: "unsupported format specifier", Span: v.Span}},
+			}
+		}
+	}
+
+	if v, ok := call.FlagValue("key-codec"); ok {
+		if _, ok := keyCodecs[v.Value.(string)]; !ok {
+			return "", nu.Error{
+				Err:    fmt.Errorf("unsupported key codec %q", v.Value),
+				Help:   `Valid key codecs are: "u64be", "i64be", "unixnano", "uuid", "ulid"`,
+				Labels: []nu.Label{{Text: "unsupported codec", Span: v.Span}},
+			}
+		}
+	}
+
 	// inputs
-	if action != "set" && (len(call.Positional) == 3 || call.Input != nil) {
+	if (action != "set" && len(call.Positional) == 3) || (call.Input != nil && !slices.Contains([]string{"set", "get", "tx"}, action)) {
 		return "", fmt.Errorf(`action %q doesn't accept input`, action)
 	}
+	if action == "tx" && call.Input == nil {
+		return "", fmt.Errorf(`action %q requires list of operations as input`, action)
+	}
+	if keys && call.Input != nil {
+		return "", nu.Error{
+			Err:    errors.New(`list of keys can't be given both by the "keys" flag and as input`),
+			Labels: []nu.Label{{Text: "choose one", Span: keysValue.Span}},
+		}
+	}
 	if len(call.Positional) == 3 && call.Input != nil {
 		return "", fmt.Errorf(`both "data" argument and input can't be used at the same time`)
 		/*return "", nu.Error{
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+/*
+move moves key or bucket to the new location (within the same database).
+
+For the "rename" action the "dest" flag is the new name of the key or bucket
+(ie it stays in the same parent bucket), for the "move" action it is the path
+of the destination bucket.
+*/
+func move(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand, action string) error {
+	path, key, err := location(call)
+	if err != nil {
+		return err
+	}
+	destValue, _ := call.FlagValue("dest")
+
+	if key != nil {
+		destPath, destKey := path, *key
+		if action == "rename" {
+			if destKey.name, err = getKeyEncoder(call)(destValue); err != nil {
+				return fmt.Errorf("invalid destination key name: %w", err)
+			}
+			destKey.span = destValue.Span
+		} else {
+			if destPath, err = toPath(destValue); err != nil {
+				return fmt.Errorf("invalid destination bucket name: %w", err)
+			}
+			if v, ok := call.FlagValue("dest-key"); ok {
+				if destKey.name, err = getKeyEncoder(call)(v); err != nil {
+					return fmt.Errorf("invalid destination key name: %w", err)
+				}
+				destKey.span = v.Span
+			}
+		}
+		_, err = update(ctx, db, call, func(tx *writeTx) error {
+			return moveKey(tx, path, *key, destPath, destKey)
+		})
+		return err
+	}
+
+	if len(path) == 0 {
+		// checked here as building the destination path of the rename needs the parent
+		return nu.Error{
+			Err:    errors.New("root bucket can't be moved nor renamed"),
+			Labels: []nu.Label{{Text: "empty bucket path", Span: call.Named["bucket"].Span}},
+		}
+	}
+	var destPath []boltItem
+	if action == "rename" {
+		name, err := toBytes(destValue)
+		if err != nil {
+			return fmt.Errorf("invalid destination bucket name: %w", err)
+		}
+		destPath = append(path[:len(path)-1:len(path)-1], boltItem{name: name, span: destValue.Span})
+	} else if destPath, err = toPath(destValue); err != nil {
+		return fmt.Errorf("invalid destination bucket name: %w", err)
+	}
+	_, err = update(ctx, db, call, func(tx *writeTx) error {
+		return moveBucket(tx, path, destPath)
+	})
+	return err
+}
+
+func moveKey(tx *writeTx, srcPath []boltItem, srcKey boltItem, destPath []boltItem, destKey boltItem) error {
+	src, err := tx.goToBucket(srcPath)
+	if err != nil {
+		return err
+	}
+	dst, err := tx.goToBucket(destPath)
+	if err != nil {
+		return err
+	}
+
+	v := src.Get(srcKey.name)
+	if v == nil {
+		return (&nu.Error{Err: fmt.Errorf("key %x doesn't exist", srcKey.name)}).AddLabel("no such key", srcKey.span)
+	}
+	if dst.Get(destKey.name) != nil || dst.Bucket(destKey.name) != nil {
+		return (&nu.Error{Err: fmt.Errorf("key %x already exists in the destination bucket", destKey.name)}).AddLabel("destination exists", destKey.span)
+	}
+
+	if err := tx.put(dst, destKey.name, bytes.Clone(v)); err != nil {
+		return fmt.Errorf("writing destination key: %w", err)
+	}
+	return tx.deleteKey(src, srcKey.name)
+}
+
+func moveBucket(tx *writeTx, srcPath, destPath []boltItem) error {
+	if len(srcPath) == 0 || len(destPath) == 0 {
+		return errors.New("root bucket can't be moved nor be the destination")
+	}
+	if isPrefixPath(srcPath, destPath) {
+		return (&nu.Error{Err: errors.New("bucket can't be moved into itself")}).AddLabel("destination inside the source bucket", destPath[len(destPath)-1].span)
+	}
+
+	src, err := tx.goToBucket(srcPath)
+	if err != nil {
+		return err
+	}
+	// can't fail as the src exists
+	srcParent, _ := tx.goToBucket(srcPath[:len(srcPath)-1])
+
+	dstParent, err := tx.createBucket(destPath[:len(destPath)-1])
+	if err != nil {
+		return err
+	}
+	destName := destPath[len(destPath)-1]
+	if destName.match != nil {
+		return errWildcard(destName)
+	}
+	dst, err := tx.createBucketIn(dstParent, destName.name, false)
+	if err != nil {
+		return (&nu.Error{Err: fmt.Errorf("creating destination bucket: %w", err)}).AddLabel("invalid destination", destName.span)
+	}
+
+	var cs copyStats
+	if err := cs.copyBucket(tx, dst, src, true); err != nil {
+		return fmt.Errorf("copying bucket: %w", err)
+	}
+	return tx.deleteBucket(srcParent, srcPath[len(srcPath)-1].name)
+}
+
+// isPrefixPath returns true when the path starts with the prefix.
+func isPrefixPath(prefix, path []boltItem) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i, v := range prefix {
+		if !bytes.Equal(v.name, path[i].name) {
+			return false
+		}
+	}
+	return true
+}
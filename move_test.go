@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+func Test_moveBucket(t *testing.T) {
+	db := testDB(t, "a", "b")
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("test"))
+		if err := b.SetSequence(42); err != nil {
+			return err
+		}
+		nb, err := b.CreateBucket([]byte("nested"))
+		if err != nil {
+			return err
+		}
+		return nb.Put([]byte("k"), []byte("v"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := func(names ...string) (p []boltItem) {
+		for _, n := range names {
+			p = append(p, boltItem{name: []byte(n)})
+		}
+		return p
+	}
+
+	t.Run("into itself", func(t *testing.T) {
+		err := db.Update(func(tx *bbolt.Tx) error {
+			return moveBucket(&writeTx{Tx: tx}, path("test"), path("test", "nested", "foo"))
+		})
+		if err == nil {
+			t.Error("expected error when moving bucket into itself")
+		}
+	})
+
+	t.Run("to existing bucket", func(t *testing.T) {
+		err := db.Update(func(tx *bbolt.Tx) error {
+			return moveBucket(&writeTx{Tx: tx}, path("test", "nested"), path("test"))
+		})
+		if err == nil {
+			t.Error("expected error when destination exists")
+		}
+	})
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		return moveBucket(&writeTx{Tx: tx}, path("test"), path("archive", "moved"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.View(func(tx *bbolt.Tx) error {
+		if tx.Bucket([]byte("test")) != nil {
+			t.Error("source bucket still exists")
+		}
+		b, err := goToBucket(tx, path("archive", "moved"))
+		if err != nil {
+			return err
+		}
+		if seq := b.Sequence(); seq != 42 {
+			t.Errorf("expected sequence 42, got %d", seq)
+		}
+		if v := b.Get([]byte("a")); string(v) != "value of a" {
+			t.Errorf("unexpected value of key a: %q", v)
+		}
+		if v := b.Bucket([]byte("nested")).Get([]byte("k")); string(v) != "v" {
+			t.Errorf("unexpected value of nested key: %q", v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
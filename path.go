@@ -3,6 +3,8 @@ package main
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
 
 	"go.etcd.io/bbolt"
 
@@ -11,21 +13,25 @@ import (
 
 /*
 boltItem is bucket or key name in bbolt database.
+
+Bucket path might contain wildcard items (match is assigned) which match
+multiple sibling buckets.
 */
 type boltItem struct {
-	name []byte
-	span nu.Span
+	name  []byte
+	span  nu.Span
+	match func(name []byte) bool
 }
 
 func toPath(v nu.Value) (path []boltItem, _ error) {
 	switch t := v.Value.(type) {
 	case []nu.Value:
 		for _, v := range t {
-			b, err := toBytes(v)
+			item, err := toPathItem(v)
 			if err != nil {
 				return nil, err
 			}
-			path = append(path, boltItem{name: b, span: v.Span})
+			path = append(path, item)
 		}
 		return path, nil
 	case nu.CellPath:
@@ -40,21 +46,156 @@ func toPath(v nu.Value) (path []boltItem, _ error) {
 			if !v.CaseSensitive() {
 				return nil, nu.Error{Err: errors.New("case-insensitive path members are not supported"), Labels: []nu.Label{{Text: "case-insensitive members not supported", Span: v.Span()}}}
 			}
-			path = append(path, boltItem{name: []byte(v.PathStr()), span: v.Span()})
+			if v.PathStr() == "*" {
+				path = append(path, boltItem{span: v.Span(), match: func([]byte) bool { return true }})
+			} else {
+				path = append(path, boltItem{name: []byte(v.PathStr()), span: v.Span()})
+			}
 		}
 		return path, nil
 	default:
-		b, err := toBytes(v)
-		return []boltItem{{name: b, span: v.Span}}, err
+		item, err := toPathItem(v)
+		return []boltItem{item}, err
 	}
 }
 
+/*
+toPathItem converts single item of the bucket path. In addition to the
+values supported by toBytes wildcards are supported:
+  - string "*" matches any bucket (use binary 0x[2a] for the bucket named "*");
+  - record {match: <regex>} matches buckets whose name matches the regex;
+*/
+func toPathItem(v nu.Value) (boltItem, error) {
+	switch t := v.Value.(type) {
+	case string:
+		if t == "*" {
+			return boltItem{span: v.Span, match: func([]byte) bool { return true }}, nil
+		}
+	case nu.Record:
+		rv, ok := t["match"]
+		if s, isStr := rv.Value.(string); ok && isStr && len(t) == 1 {
+			reg, err := regexp.Compile(s)
+			if err != nil {
+				return boltItem{}, regexpError(err, rv.Span)
+			}
+			return boltItem{span: v.Span, match: reg.Match}, nil
+		}
+		return boltItem{}, nu.Error{
+			Err:    errors.New("unsupported bucket path item"),
+			Help:   "Record is supported as bucket path item in the form {match: <regex>}",
+			Labels: []nu.Label{{Text: "expected {match: <regex>}", Span: v.Span}},
+		}
+	}
+
+	b, err := toBytes(v)
+	return boltItem{name: b, span: v.Span}, err
+}
+
+// hasWildcards returns true when the path contains items which match multiple buckets.
+func hasWildcards(path []boltItem) bool {
+	return slices.ContainsFunc(path, func(item boltItem) bool { return item.match != nil })
+}
+
 func goToBucket(tx *bbolt.Tx, path []boltItem) (*bbolt.Bucket, error) {
 	b := tx.Cursor().Bucket()
 	for _, v := range path {
+		if v.match != nil {
+			return nil, errWildcard(v)
+		}
 		if b = b.Bucket(v.name); b == nil {
 			return nil, (&nu.Error{Err: fmt.Errorf("bucket %x doesn't exist", v.name)}).AddLabel("no such bucket", v.span)
 		}
 	}
 	return b, nil
 }
+
+// itemNames returns the names of the path items.
+func itemNames(path []boltItem) [][]byte {
+	names := make([][]byte, len(path))
+	for i, v := range path {
+		names[i] = v.name
+	}
+	return names
+}
+
+func errWildcard(item boltItem) error {
+	return nu.Error{
+		Err:    errors.New("wildcards in the bucket path are not supported by the action"),
+		Help:   `Wildcards are supported by actions "buckets", "keys", "get", "count" and "delete"`,
+		Labels: []nu.Label{{Text: "wildcard not allowed", Span: item.span}},
+	}
+}
+
+/*
+bucketMatch is a bucket found by findBuckets.
+*/
+type bucketMatch struct {
+	path     [][]byte // names of the buckets starting from the root
+	bucket   *bbolt.Bucket
+	wildcard bool // was the bucket found using path with wildcards
+}
+
+/*
+findBuckets returns all the buckets matching the path. When the path doesn't
+contain wildcards it behaves like goToBucket, ie returns error when the
+bucket doesn't exist. Otherwise the non-existing branches are silently
+skipped and result might be empty.
+*/
+func findBuckets(tx *bbolt.Tx, path []boltItem) ([]bucketMatch, error) {
+	if !hasWildcards(path) {
+		b, err := goToBucket(tx, path)
+		if err != nil {
+			return nil, err
+		}
+		return []bucketMatch{{path: itemNames(path), bucket: b}}, nil
+	}
+
+	r := []bucketMatch{{bucket: tx.Cursor().Bucket(), wildcard: true}}
+	for _, item := range path {
+		var next []bucketMatch
+		for _, m := range r {
+			if item.match == nil {
+				if b := m.bucket.Bucket(item.name); b != nil {
+					next = append(next, bucketMatch{path: append(slices.Clip(m.path), item.name), bucket: b, wildcard: true})
+				}
+				continue
+			}
+
+			err := m.bucket.ForEachBucket(func(k []byte) error {
+				if item.match(k) {
+					next = append(next, bucketMatch{path: append(slices.Clip(m.path), k), bucket: m.bucket.Bucket(k), wildcard: true})
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		r = next
+	}
+	return r, nil
+}
+
+/*
+tagger returns func which adds the path of the bucket to the output row when
+the bucket was found using wildcards (otherwise rows of different buckets
+would be indistinguishable). Records get "bucket" field, other values are
+wrapped into record {bucket: <path>, <field>: <row>}.
+*/
+func (m bucketMatch) tagger(format func([]byte) nu.Value) func(row nu.Value, field string) nu.Value {
+	if !m.wildcard {
+		return func(row nu.Value, field string) nu.Value { return row }
+	}
+
+	path := make([]nu.Value, len(m.path))
+	for i, v := range m.path {
+		path[i] = format(v)
+	}
+	return func(row nu.Value, field string) nu.Value {
+		if r, ok := row.Value.(nu.Record); ok {
+			r["bucket"] = nu.Value{Value: path}
+			return row
+		}
+		return nu.Value{Value: nu.Record{"bucket": nu.Value{Value: path}, field: row}}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+func Test_findBuckets(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// tenants -> {t1 -> sessions, t2 -> users, t3 -> sessions}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("tenants"))
+		if err != nil {
+			return err
+		}
+		for _, p := range [][2]string{{"t1", "sessions"}, {"t2", "users"}, {"t3", "sessions"}} {
+			tb, err := b.CreateBucket([]byte(p[0]))
+			if err != nil {
+				return err
+			}
+			if _, err := tb.CreateBucket([]byte(p[1])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pathOf := func(t *testing.T, items ...any) []boltItem {
+		t.Helper()
+		v := make([]nu.Value, len(items))
+		for i, item := range items {
+			v[i] = nu.Value{Value: item}
+		}
+		path, err := toPath(nu.Value{Value: v})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	var testCases = []struct {
+		path  []boltItem
+		found []string
+	}{
+		{path: pathOf(t, "tenants", "t1"), found: []string{"tenants/t1"}},
+		{path: pathOf(t, "tenants", "*"), found: []string{"tenants/t1", "tenants/t2", "tenants/t3"}},
+		{path: pathOf(t, "tenants", "*", "sessions"), found: []string{"tenants/t1/sessions", "tenants/t3/sessions"}},
+		{path: pathOf(t, "tenants", nu.Record{"match": nu.Value{Value: "[12]"}}, "*"), found: []string{"tenants/t1/sessions", "tenants/t2/users"}},
+		{path: pathOf(t, "tenants", "*", "foo"), found: nil},
+		{path: pathOf(t, "*", "*", "*"), found: []string{"tenants/t1/sessions", "tenants/t2/users", "tenants/t3/sessions"}},
+	}
+
+	for i, tc := range testCases {
+		err := db.View(func(tx *bbolt.Tx) error {
+			buckets, err := findBuckets(tx, tc.path)
+			if err != nil {
+				return err
+			}
+			var found []string
+			for _, m := range buckets {
+				found = append(found, string(bytes.Join(m.path, []byte("/"))))
+			}
+			if !slices.Equal(found, tc.found) {
+				t.Errorf("[%d] expected %q, got %q", i, tc.found, found)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+		}
+	}
+
+	t.Run("missing bucket without wildcards", func(t *testing.T) {
+		err := db.View(func(tx *bbolt.Tx) error {
+			_, err := findBuckets(tx, pathOf(t, "tenants", "t4"))
+			return err
+		})
+		if err == nil {
+			t.Error("expected error for missing bucket")
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+/*
+keyRange describes the subset of the items in a bucket to iterate over.
+Zero value means "all items".
+*/
+type keyRange struct {
+	from    []byte // first key to include, nil means start from the first key
+	to      []byte // last key, nil means iterate until the end of the bucket
+	toIncl  bool   // is the "to" key itself included
+	prefix  []byte // only keys with given prefix are included
+	after   []byte // resume iteration after this key (in iteration order), the key itself is not included
+	reverse bool   // iterate in descending order
+	skip    int64  // number of matching items to skip
+	limit   int64  // max number of items to return, zero means no limit
+	token   bool   // send continuation token when the output is truncated by limit
+}
+
+/*
+forEach calls fn for every item (key or bucket, for buckets v is nil) in
+the range which is accepted by the match func. Cursor.Seek is used to
+position the cursor so items before the range are never visited and
+iteration stops as soon as the end of the range or the limit is reached.
+
+The returned flag "truncated" is true when the iteration was stopped
+because of the limit and there are more items in the range (which might
+or might not be accepted by the match func).
+*/
+func (r keyRange) forEach(c *bbolt.Cursor, match func(k, v []byte) bool, fn func(k, v []byte) error) (truncated bool, _ error) {
+	k, v, next := r.first(c)
+	skip, cnt := r.skip, int64(0)
+	for ; k != nil && r.contains(k); k, v = next() {
+		if !match(k, v) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		if err := fn(k, v); err != nil {
+			return false, err
+		}
+		if cnt++; r.limit > 0 && cnt == r.limit {
+			k, _ = next()
+			return k != nil && r.contains(k), nil
+		}
+	}
+	return false, nil
+}
+
+/*
+first positions the cursor to the first item of the range (in the iteration
+order) and returns it together with the func to move to the next item.
+*/
+func (r keyRange) first(c *bbolt.Cursor) (k, v []byte, next func() ([]byte, []byte)) {
+	if !r.reverse {
+		if start := r.start(); start != nil {
+			k, v = c.Seek(start)
+		} else {
+			k, v = c.First()
+		}
+		for k != nil && r.beforeStart(k) {
+			k, v = c.Next()
+		}
+		return k, v, c.Next
+	}
+
+	if end := r.end(); end != nil {
+		if k, v = c.Seek(end); k == nil {
+			k, v = c.Last()
+		}
+	} else {
+		k, v = c.Last()
+	}
+	for k != nil && r.afterEnd(k) {
+		k, v = c.Prev()
+	}
+	return k, v, c.Prev
+}
+
+/*
+start returns the key to seek to, the key might be before the start of
+the range. Nil means start from the first key.
+*/
+func (r keyRange) start() []byte {
+	start := r.from
+	for _, k := range [][]byte{r.prefix, r.after} {
+		if bytes.Compare(k, start) > 0 {
+			start = k
+		}
+	}
+	return start
+}
+
+/*
+end returns the key to seek to when iterating in reverse order, the key
+might be past the end of the range. Nil means start from the last key.
+*/
+func (r keyRange) end() []byte {
+	end := r.to
+	for _, k := range [][]byte{prefixEnd(r.prefix), r.after} {
+		if k != nil && (end == nil || bytes.Compare(k, end) < 0) {
+			end = k
+		}
+	}
+	return end
+}
+
+// bounded returns true when the range doesn't include all the keys.
+func (r keyRange) bounded() bool {
+	return r.from != nil || r.to != nil || r.prefix != nil || r.after != nil
+}
+
+// contains returns true when the key k is within the range.
+func (r keyRange) contains(k []byte) bool {
+	return !r.beforeStart(k) && !r.afterEnd(k)
+}
+
+// beforeStart returns true when the key k is before the start of the range.
+func (r keyRange) beforeStart(k []byte) bool {
+	if !r.reverse && r.after != nil && bytes.Compare(k, r.after) <= 0 {
+		return true
+	}
+	return bytes.Compare(k, r.from) < 0 || bytes.Compare(k, r.prefix) < 0
+}
+
+// afterEnd returns true when the key k is past the end of the range.
+func (r keyRange) afterEnd(k []byte) bool {
+	if r.prefix != nil && !bytes.HasPrefix(k, r.prefix) && bytes.Compare(k, r.prefix) > 0 {
+		return true
+	}
+	if r.reverse && r.after != nil && bytes.Compare(k, r.after) >= 0 {
+		return true
+	}
+	if r.to == nil {
+		return false
+	}
+	cmp := bytes.Compare(k, r.to)
+	return cmp > 0 || (cmp == 0 && !r.toIncl)
+}
+
+/*
+prefixEnd returns the smallest key which is greater than all the keys with
+given prefix. Nil is returned when there is no such key (prefix is empty or
+consists of 0xFF bytes only).
+*/
+func prefixEnd(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xFF {
+			end := bytes.Clone(prefix[:i+1])
+			end[i]++
+			return end
+		}
+	}
+	return nil
+}
+
+/*
+sendToken sends the continuation token as the last item of the output stream
+if it was requested and the output was truncated. The "last" is the name of
+the last item sent to the output.
+*/
+func (r keyRange) sendToken(out chan<- nu.Value, truncated bool, last []byte, tag func(nu.Value, string) nu.Value) {
+	if r.token && truncated {
+		out <- tag(nu.Value{Value: nu.Record{"after": nu.Value{Value: slices.Clone(last)}}}, "")
+	}
+}
+
+/*
+keysOnly returns match func for keyRange.forEach which accepts keys (but
+not buckets) whose name is accepted by the filter and value by the
+valueFilter.
+*/
+func keysOnly(filter, valueFilter func([]byte) bool) func(k, v []byte) bool {
+	return func(k, v []byte) bool { return v != nil && filter(k) && valueFilter(v) }
+}
+
+/*
+bucketsOnly returns match func for keyRange.forEach which accepts buckets
+(but not keys) accepted by the filter.
+*/
+func bucketsOnly(filter func([]byte) bool) func(k, v []byte) bool {
+	return func(k, v []byte) bool { return v == nil && filter(k) }
+}
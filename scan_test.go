@@ -0,0 +1,184 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+func Test_keyRange(t *testing.T) {
+	db := testDB(t, "a", "b", "c", "d", "e")
+
+	var testCases = []struct {
+		rng  keyRange
+		keys []string
+	}{
+		{rng: keyRange{}, keys: []string{"a", "b", "c", "d", "e"}},
+		{rng: keyRange{from: []byte("c")}, keys: []string{"c", "d", "e"}},
+		{rng: keyRange{from: []byte("bb")}, keys: []string{"c", "d", "e"}},
+		{rng: keyRange{from: []byte("f")}, keys: nil},
+		{rng: keyRange{to: []byte("c")}, keys: []string{"a", "b"}},
+		{rng: keyRange{to: []byte("c"), toIncl: true}, keys: []string{"a", "b", "c"}},
+		{rng: keyRange{to: []byte("cc"), toIncl: true}, keys: []string{"a", "b", "c"}},
+		{rng: keyRange{from: []byte("b"), to: []byte("d")}, keys: []string{"b", "c"}},
+		{rng: keyRange{from: []byte("b"), to: []byte("d"), toIncl: true}, keys: []string{"b", "c", "d"}},
+		{rng: keyRange{from: []byte("d"), to: []byte("b")}, keys: nil},
+		// reverse order
+		{rng: keyRange{reverse: true}, keys: []string{"e", "d", "c", "b", "a"}},
+		{rng: keyRange{reverse: true, from: []byte("c")}, keys: []string{"e", "d", "c"}},
+		{rng: keyRange{reverse: true, to: []byte("c")}, keys: []string{"b", "a"}},
+		{rng: keyRange{reverse: true, to: []byte("c"), toIncl: true}, keys: []string{"c", "b", "a"}},
+		{rng: keyRange{reverse: true, to: []byte("cc")}, keys: []string{"c", "b", "a"}},
+		{rng: keyRange{reverse: true, to: []byte("x")}, keys: []string{"e", "d", "c", "b", "a"}},
+		{rng: keyRange{reverse: true, from: []byte("b"), to: []byte("d")}, keys: []string{"c", "b"}},
+		// paging
+		{rng: keyRange{limit: 2}, keys: []string{"a", "b"}},
+		{rng: keyRange{skip: 1, limit: 2}, keys: []string{"b", "c"}},
+		{rng: keyRange{skip: 4, limit: 2}, keys: []string{"e"}},
+		{rng: keyRange{skip: 5}, keys: nil},
+		{rng: keyRange{reverse: true, limit: 2}, keys: []string{"e", "d"}},
+		{rng: keyRange{reverse: true, skip: 1, limit: 2, to: []byte("d")}, keys: []string{"b", "a"}},
+		// resume after key
+		{rng: keyRange{after: []byte("b")}, keys: []string{"c", "d", "e"}},
+		{rng: keyRange{after: []byte("bb")}, keys: []string{"c", "d", "e"}},
+		{rng: keyRange{after: []byte("e")}, keys: nil},
+		{rng: keyRange{after: []byte("b"), from: []byte("a"), limit: 2}, keys: []string{"c", "d"}},
+		{rng: keyRange{after: []byte("b"), from: []byte("d")}, keys: []string{"d", "e"}},
+		{rng: keyRange{after: []byte("d"), reverse: true}, keys: []string{"c", "b", "a"}},
+		{rng: keyRange{after: []byte("d"), reverse: true, to: []byte("c")}, keys: []string{"b", "a"}},
+		{rng: keyRange{after: []byte("a"), reverse: true}, keys: nil},
+	}
+
+	for i, tc := range testCases {
+		keys := collectKeys(t, db, tc.rng)
+		if !slices.Equal(keys, tc.keys) {
+			t.Errorf("[%d] expected %q, got %q", i, tc.keys, keys)
+		}
+	}
+}
+
+func Test_keyRange_prefix(t *testing.T) {
+	db := testDB(t, "a", "ba", "bb", "bc", "c", "user\x00a", "user\x00b", "user\x01a")
+
+	var testCases = []struct {
+		rng  keyRange
+		keys []string
+	}{
+		{rng: keyRange{prefix: []byte("b")}, keys: []string{"ba", "bb", "bc"}},
+		{rng: keyRange{prefix: []byte("bb")}, keys: []string{"bb"}},
+		{rng: keyRange{prefix: []byte("x")}, keys: nil},
+		{rng: keyRange{prefix: []byte("user\x00")}, keys: []string{"user\x00a", "user\x00b"}},
+		{rng: keyRange{prefix: []byte("b"), from: []byte("a")}, keys: []string{"ba", "bb", "bc"}},
+		{rng: keyRange{prefix: []byte("b"), from: []byte("bb")}, keys: []string{"bb", "bc"}},
+		{rng: keyRange{prefix: []byte("b"), to: []byte("bc")}, keys: []string{"ba", "bb"}},
+		{rng: keyRange{prefix: []byte("b"), from: []byte("c")}, keys: nil},
+		{rng: keyRange{prefix: []byte("b"), reverse: true}, keys: []string{"bc", "bb", "ba"}},
+		{rng: keyRange{prefix: []byte("b"), reverse: true, to: []byte("bc")}, keys: []string{"bb", "ba"}},
+		{rng: keyRange{prefix: []byte("user\x00"), reverse: true}, keys: []string{"user\x00b", "user\x00a"}},
+		{rng: keyRange{prefix: []byte("x"), reverse: true}, keys: nil},
+	}
+
+	for i, tc := range testCases {
+		keys := collectKeys(t, db, tc.rng)
+		if !slices.Equal(keys, tc.keys) {
+			t.Errorf("[%d] expected %q, got %q", i, tc.keys, keys)
+		}
+	}
+}
+
+func Test_keyRange_truncated(t *testing.T) {
+	db := testDB(t, "a", "b", "c", "d", "e")
+
+	var testCases = []struct {
+		rng       keyRange
+		truncated bool
+	}{
+		{rng: keyRange{}, truncated: false},
+		{rng: keyRange{limit: 5}, truncated: false},
+		{rng: keyRange{limit: 4}, truncated: true},
+		{rng: keyRange{limit: 2, to: []byte("c")}, truncated: false},
+		{rng: keyRange{limit: 2, to: []byte("c"), toIncl: true}, truncated: true},
+		{rng: keyRange{limit: 2, reverse: true}, truncated: true},
+		{rng: keyRange{limit: 2, reverse: true, after: []byte("c")}, truncated: false},
+	}
+
+	for i, tc := range testCases {
+		err := db.View(func(tx *bbolt.Tx) error {
+			truncated, err := tc.rng.forEach(tx.Bucket([]byte("test")).Cursor(), func(k, v []byte) bool { return true }, func(k, v []byte) error { return nil })
+			if truncated != tc.truncated {
+				t.Errorf("[%d] expected truncated to be %t", i, tc.truncated)
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_prefixEnd(t *testing.T) {
+	var testCases = []struct {
+		in, out []byte
+	}{
+		{in: nil, out: nil},
+		{in: []byte{}, out: nil},
+		{in: []byte{0xFF}, out: nil},
+		{in: []byte{0xFF, 0xFF}, out: nil},
+		{in: []byte{0}, out: []byte{1}},
+		{in: []byte{1, 0xFF}, out: []byte{2}},
+		{in: []byte{1, 0xFE}, out: []byte{1, 0xFF}},
+		{in: []byte("abc"), out: []byte("abd")},
+	}
+
+	for i, tc := range testCases {
+		if out := prefixEnd(tc.in); !slices.Equal(out, tc.out) {
+			t.Errorf("[%d] expected %x, got %x", i, tc.out, out)
+		}
+	}
+}
+
+func collectKeys(t *testing.T, db *bbolt.DB, rng keyRange) (keys []string) {
+	t.Helper()
+	err := db.View(func(tx *bbolt.Tx) error {
+		_, err := rng.forEach(tx.Bucket([]byte("test")).Cursor(), func(k, v []byte) bool { return true }, func(k, v []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+/*
+testDB creates new database with bucket "test" containing given keys.
+*/
+func testDB(t *testing.T, keys ...string) *bbolt.DB {
+	t.Helper()
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("test"))
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Put([]byte(k), []byte("value of "+k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+/*
+sequence returns the sequence of the bucket. With the "set" flag the
+sequence is changed to given value, with the "next" flag the sequence is
+incremented and the new value is returned.
+*/
+func sequence(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
+	path, _, err := location(call)
+	if err != nil {
+		return err
+	}
+
+	var set *uint64
+	if v, ok := call.FlagValue("set"); ok {
+		n, err := nonNegative(v)
+		if err != nil {
+			return err
+		}
+		seq := uint64(n)
+		set = &seq
+	}
+	next := boolFlag(call, "next")
+
+	var seq uint64
+	op := sequenceOp(set, next)
+	run := func(tx *writeTx) error {
+		b, err := tx.goToBucket(path)
+		if err != nil {
+			return err
+		}
+		seq, err = op(tx, b)
+		return err
+	}
+	if set == nil && !next {
+		// reading the sequence doesn't need write transaction
+		err = db.View(func(tx *bbolt.Tx) error { return run(&writeTx{Tx: tx}) })
+	} else {
+		var sent bool
+		if sent, err = update(ctx, db, call, run); sent {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return call.ReturnValue(ctx, nu.ToValue(seq))
+}
+
+/*
+sequenceOp returns func which sets the sequence of the bucket (when "set" is
+not nil), increments it (when "next" is true) or just reads it. The func
+returns the (new) sequence of the bucket.
+*/
+func sequenceOp(set *uint64, next bool) func(tx *writeTx, b *bbolt.Bucket) (uint64, error) {
+	switch {
+	case set != nil:
+		return func(tx *writeTx, b *bbolt.Bucket) (uint64, error) { return *set, tx.setSequence(b, *set) }
+	case next:
+		return (*writeTx).nextSequence
+	default:
+		return func(tx *writeTx, b *bbolt.Bucket) (uint64, error) { return b.Sequence(), nil }
+	}
+}
+
+/*
+withSequence adds the sequence of the bucket as field "Sequence" to the
+record v (output of the "stat" and "info" actions).
+*/
+func withSequence(v nu.Value, b *bbolt.Bucket) nu.Value {
+	if r, ok := v.Value.(nu.Record); ok {
+		r["Sequence"] = nu.ToValue(b.Sequence())
+	}
+	return v
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+func Test_sequenceOp(t *testing.T) {
+	db := testDB(t, "a")
+	path := []boltItem{{name: []byte("test")}}
+	set := func(n uint64) *uint64 { return &n }
+
+	// run executes op in the bucket "test" and returns the sequence op returned
+	run := func(t *testing.T, tx *writeTx, op func(tx *writeTx, b *bbolt.Bucket) (uint64, error)) uint64 {
+		t.Helper()
+		b, err := tx.goToBucket(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seq, err := op(tx, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return seq
+	}
+	// current returns the sequence of the bucket "test" in the database
+	current := func(t *testing.T) (seq uint64) {
+		t.Helper()
+		err := db.View(func(tx *bbolt.Tx) error {
+			seq = run(t, &writeTx{Tx: tx}, sequenceOp(nil, false))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return seq
+	}
+
+	t.Run("get, set and next", func(t *testing.T) {
+		if seq := current(t); seq != 0 {
+			t.Errorf("expected initial sequence 0, got %d", seq)
+		}
+		err := db.Update(func(tx *bbolt.Tx) error {
+			wtx := &writeTx{Tx: tx}
+			if seq := run(t, wtx, sequenceOp(set(41), false)); seq != 41 {
+				t.Errorf("expected set to return 41, got %d", seq)
+			}
+			if seq := run(t, wtx, sequenceOp(nil, true)); seq != 42 {
+				t.Errorf("expected next to return 42, got %d", seq)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq := current(t); seq != 42 {
+			t.Errorf("expected sequence 42, got %d", seq)
+		}
+	})
+
+	t.Run("dry-run", func(t *testing.T) {
+		before := current(t)
+		var testCases = []struct {
+			name string
+			op   func(tx *writeTx, b *bbolt.Bucket) (uint64, error)
+			seq  uint64
+		}{
+			{name: "set", op: sequenceOp(set(100), false), seq: 100},
+			{name: "next", op: sequenceOp(nil, true), seq: before + 1},
+		}
+		for _, tc := range testCases {
+			tx, err := db.Begin(true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			log := newChangeLog(func(b []byte) nu.Value { return nu.Value{Value: string(b)} })
+			seq := run(t, &writeTx{Tx: tx, log: log}, tc.op)
+			if err := tx.Rollback(); err != nil {
+				t.Fatal(err)
+			}
+
+			if seq != tc.seq {
+				t.Errorf("%s: expected %d, got %d", tc.name, tc.seq, seq)
+			}
+			if len(log.sequences) != 1 {
+				t.Errorf("%s: expected one recorded sequence change, got %d", tc.name, len(log.sequences))
+				continue
+			}
+			r := log.sequences[0].Value.(nu.Record)
+			if r["old"].Value != int64(before) || r["new"].Value != int64(tc.seq) {
+				t.Errorf("%s: expected change %d -> %d, got %v -> %v", tc.name, before, tc.seq, r["old"].Value, r["new"].Value)
+			}
+			if p := r["bucket"].Value.([]nu.Value); len(p) != 1 || p[0].Value != "test" {
+				t.Errorf("%s: unexpected bucket path %v", tc.name, p)
+			}
+		}
+		// dry-run changes are rolled back
+		if seq := current(t); seq != before {
+			t.Errorf("expected sequence %d after dry-run, got %d", before, seq)
+		}
+	})
+}
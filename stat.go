@@ -22,7 +22,7 @@ func stat(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
 		if err != nil {
 			return err
 		}
-		return call.ReturnValue(ctx, nu.ToValue(b.Stats()))
+		return call.ReturnValue(ctx, withSequence(nu.ToValue(b.Stats()), b))
 	})
 }
 
@@ -36,6 +36,9 @@ func info(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
 		if err != nil {
 			return err
 		}
-		return call.ReturnValue(ctx, nu.ToValue(b.Inspect()))
+		if len(path) == 0 {
+			return call.ReturnValue(ctx, nu.ToValue(b.Inspect()))
+		}
+		return call.ReturnValue(ctx, withSequence(nu.ToValue(b.Inspect()), b))
 	})
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+/*
+applyOps executes list of operations (given as input) in a single write
+transaction. When any of the operations fails the transaction is rolled back.
+*/
+func applyOps(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
+	path, _, err := location(call)
+	if err != nil {
+		return err
+	}
+	next, err := inputRecords(call)
+	if err != nil {
+		return err
+	}
+
+	_, err = update(ctx, db, call, func(tx *writeTx) error {
+		return applyOpList(ctx, tx, next, path, getKeyEncoder(call))
+	})
+	return err
+}
+
+/*
+applyOpList executes operations returned by next in the transaction, the
+error returned for failed operation is labeled with the operation's span.
+Keys of the operations are converted to bytes by encodeKey.
+*/
+func applyOpList(ctx context.Context, tx *writeTx, next func() (nu.Value, bool), defBucket []boltItem, encodeKey func(nu.Value) ([]byte, error)) error {
+	for idx := 0; ; idx++ {
+		v, ok := next()
+		if !ok {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := applyOp(tx, v, defBucket, encodeKey); err != nil {
+			return nu.Error{
+				Err:    fmt.Errorf("operation %d failed, transaction was rolled back: %w", idx, err),
+				Labels: []nu.Label{{Text: "failed operation", Span: v.Span}},
+			}
+		}
+	}
+}
+
+/*
+applyOp executes single operation, one of
+
+	{op: set, bucket, key, value}
+	{op: delete, bucket, key} - when key is not given the bucket is deleted
+	{op: add, bucket}
+
+When operation doesn't have "bucket" field the defBucket is used.
+*/
+func applyOp(tx *writeTx, v nu.Value, defBucket []boltItem, encodeKey func(nu.Value) ([]byte, error)) error {
+	rec, ok := v.Value.(nu.Record)
+	if !ok {
+		return fmt.Errorf("expected operation to be record, got %T", v.Value)
+	}
+	op, ok := rec["op"].Value.(string)
+	if !ok {
+		return errors.New(`operation must have string field "op"`)
+	}
+
+	switch op {
+	case "set":
+		item, err := toKVRecord(v, defBucket, encodeKey)
+		if err != nil {
+			return err
+		}
+		b, err := tx.goToBucket(item.bucket)
+		if err != nil {
+			return err
+		}
+		return tx.put(b, item.key, item.value)
+	case "delete":
+		path, err := recordBucket(v, defBucket)
+		if err != nil {
+			return err
+		}
+		k, ok := rec["key"]
+		if !ok {
+			// goToBucket checks the parent path only
+			if last := path[len(path)-1]; last.match != nil {
+				return errWildcard(last)
+			}
+			b, err := tx.goToBucket(path[:len(path)-1])
+			if err != nil {
+				return err
+			}
+			return tx.deleteBucket(b, path[len(path)-1].name)
+		}
+
+		key, err := encodeKey(k)
+		if err != nil {
+			return fmt.Errorf("invalid key name: %w", err)
+		}
+		b, err := tx.goToBucket(path)
+		if err != nil {
+			return err
+		}
+		return tx.deleteKey(b, key)
+	case "add":
+		path, err := recordBucket(v, defBucket)
+		if err != nil {
+			return err
+		}
+		_, err = tx.createBucket(path)
+		return err
+	default:
+		return (&nu.Error{
+			Err:  fmt.Errorf("unknown operation %q", op),
+			Help: `Supported operations are "set", "delete" and "add"`,
+		}).AddLabel("unknown operation", rec["op"].Span)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+func Test_applyOpList(t *testing.T) {
+	op := func(fields ...string) nu.Value {
+		r := nu.Record{}
+		for i := 0; i < len(fields); i += 2 {
+			r[fields[i]] = nu.Value{Value: fields[i+1]}
+		}
+		return nu.Value{Value: r}
+	}
+	list := func(ops ...nu.Value) func() (nu.Value, bool) {
+		return func() (v nu.Value, ok bool) {
+			if len(ops) == 0 {
+				return v, false
+			}
+			v, ops = ops[0], ops[1:]
+			return v, true
+		}
+	}
+	// applyOps runs applyOpList inside db.Update (unless dry-run)
+	apply := func(db *bbolt.DB, defBucket []boltItem, ops ...nu.Value) error {
+		return db.Update(func(tx *bbolt.Tx) error {
+			return applyOpList(context.Background(), &writeTx{Tx: tx}, list(ops...), defBucket, toBytes)
+		})
+	}
+	bucket := []boltItem{{name: []byte("test")}}
+
+	t.Run("success", func(t *testing.T) {
+		db := testDB(t, "a", "b")
+		err := apply(db, bucket,
+			op("op", "set", "key", "c", "value", "new"),
+			op("op", "delete", "key", "a"),
+			op("op", "add", "bucket", "other"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := collectKeys(t, db, keyRange{}); !slices.Equal(keys, []string{"b", "c"}) {
+			t.Errorf("unexpected keys after tx: %q", keys)
+		}
+		err = db.View(func(tx *bbolt.Tx) error {
+			if tx.Bucket([]byte("other")) == nil {
+				t.Error("expected bucket \"other\" to be created")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		db := testDB(t, "a", "b")
+		failing := op("op", "frobnicate")
+		failing.Span = nu.Span{Start: 10, End: 20}
+		err := apply(db, bucket,
+			op("op", "set", "key", "c", "value", "new"),
+			op("op", "delete", "key", "a"),
+			failing,
+		)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "operation 2 failed") {
+			t.Errorf("unexpected error message: %v", err)
+		}
+		var nuErr nu.Error
+		if !errors.As(err, &nuErr) || len(nuErr.Labels) == 0 || nuErr.Labels[0].Span != failing.Span {
+			t.Errorf("expected error to be labeled with the span of the failing operation, got %#v", err)
+		}
+		// changes of the first two operations must have been rolled back
+		if keys := collectKeys(t, db, keyRange{}); !slices.Equal(keys, []string{"a", "b"}) {
+			t.Errorf("unexpected keys after rollback: %q", keys)
+		}
+	})
+
+	t.Run("key codec", func(t *testing.T) {
+		db := testDB(t, "a")
+		setOp := func(n int64) nu.Value {
+			return nu.Value{Value: nu.Record{"op": nu.Value{Value: "set"}, "key": nu.Value{Value: n}, "value": nu.Value{Value: "v"}}}
+		}
+		err := db.Update(func(tx *bbolt.Tx) error {
+			return applyOpList(context.Background(), &writeTx{Tx: tx}, list(setOp(1), setOp(300), nu.Value{Value: nu.Record{"op": nu.Value{Value: "delete"}, "key": nu.Value{Value: int64(1)}}}), bucket, keyEncoder("u64be"))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := collectKeys(t, db, keyRange{}); !slices.Equal(keys, []string{"\x00\x00\x00\x00\x00\x00\x01\x2c", "a"}) {
+			t.Errorf("unexpected keys after tx: %q", keys)
+		}
+	})
+
+	t.Run("invalid operations", func(t *testing.T) {
+		db := testDB(t, "a")
+		var testCases = []struct {
+			op  nu.Value
+			err string
+		}{
+			{op: nu.Value{Value: "set"}, err: "expected operation to be record"},
+			{op: op("key", "a"), err: `operation must have string field "op"`},
+			{op: nu.Value{Value: nu.Record{"op": nu.Value{Value: int64(1)}}}, err: `operation must have string field "op"`},
+			{op: op("op", "frobnicate"), err: `unknown operation "frobnicate"`},
+			{op: op("op", "set", "bucket", "test", "value", "v"), err: `record doesn't have "key" field`},
+			{op: op("op", "set", "bucket", "test", "key", "a"), err: `record doesn't have "value" field`},
+			{op: op("op", "delete", "key", "a"), err: "bucket is not defined"},
+			{op: op("op", "add"), err: "bucket is not defined"},
+			{op: nu.Value{Value: nu.Record{"op": nu.Value{Value: "delete"}, "bucket": nu.Value{Value: []nu.Value{{Value: "test"}, {Value: "*"}}}}}, err: "wildcards in the bucket path are not supported"},
+		}
+		for i, tc := range testCases {
+			err := apply(db, nil, tc.op)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("[%d] expected error containing %q, got %v", i, tc.err, err)
+			}
+		}
+		if keys := collectKeys(t, db, keyRange{}); !slices.Equal(keys, []string{"a"}) {
+			t.Errorf("unexpected keys after failed operations: %q", keys)
+		}
+	})
+}
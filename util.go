@@ -19,6 +19,7 @@ func toBytes(v nu.Value) ([]byte, error) {
 		}
 		return nil, &nu.Error{
 			Err:    fmt.Errorf("integer values must fit into byte, got %d", t),
+			Help:   `Use the "key-codec" flag to encode integer keys, ie "--key-codec u64be" for 8 byte big-endian integer`,
 			Labels: []nu.Label{{Text: "value out of range (max allowed 255)", Span: v.Span}},
 		}
 	case []nu.Value:
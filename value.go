@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -20,19 +21,44 @@ func getValue(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
 	if err != nil {
 		return err
 	}
+	valueFilter, _, err := getValueFilter(call)
+	if err != nil {
+		return err
+	}
+	rng, err := getRange(call)
+	if err != nil {
+		return err
+	}
 	format := getFormatter(call)
+	decode := getValueDecoder(call)
+
+	keys, err := keyList(call)
+	if err != nil {
+		return err
+	}
+	if keys != nil {
+		return getValues(ctx, db, call, path, keys, valueFilter, format, decode)
+	}
 
 	return db.View(func(tx *bbolt.Tx) error {
-		b, err := goToBucket(tx, path)
+		buckets, err := findBuckets(tx, path)
 		if err != nil {
 			return err
 		}
 
-		if key != nil {
-			if v := b.Get(key.name); v != nil {
-				return call.ReturnValue(ctx, nu.Value{Value: slices.Clone(v)})
+		if key != nil && !hasWildcards(path) {
+			v := buckets[0].bucket.Get(key.name)
+			if v == nil || !valueFilter(v) {
+				return nil
 			}
-			return nil
+			if decode != nil {
+				dv, err := decode(v)
+				if err != nil {
+					return (&nu.Error{Err: err}).AddLabel("failed to decode the value of the key", key.span)
+				}
+				return call.ReturnValue(ctx, dv)
+			}
+			return streamValue(ctx, call, v)
 		}
 
 		out, err := call.ReturnListStream(ctx)
@@ -41,19 +67,159 @@ func getValue(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
 		}
 		defer close(out)
 
-		c := b.Cursor()
-		for k, v := c.First(); k != nil; k, v = c.Next() {
-			if v != nil && filter(k) {
-				out <- nu.Value{Value: nu.Record{
-					"key":   format(k),
-					"value": nu.Value{Value: slices.Clone(v)},
-				}}
+		for _, m := range buckets {
+			tag := m.tagger(format)
+			if key != nil {
+				if v := m.bucket.Get(key.name); v != nil && valueFilter(v) {
+					row := nu.Record{"key": format(key.name)}
+					setValueField(row, v, decode)
+					out <- tag(nu.Value{Value: row}, "")
+				}
+				continue
+			}
+
+			var last []byte
+			truncated, err := rng.forEach(m.bucket.Cursor(), keysOnly(filter, valueFilter), func(k, v []byte) error {
+				row := nu.Record{"key": format(k)}
+				setValueField(row, v, decode)
+				out <- tag(nu.Value{Value: row}, "")
+				last = k
+				return nil
+			})
+			if err != nil {
+				return err
 			}
+			rng.sendToken(out, truncated, last, tag)
+		}
+		return nil
+	})
+}
+
+/*
+streamValue returns the value as binary stream. The value is written in chunks
+straight from the memory mapped database so (potentially large) value is not
+copied into memory as a whole. Must be called inside the transaction.
+*/
+func streamValue(ctx context.Context, call *nu.ExecCommand, v []byte) error {
+	out, err := call.ReturnRawStream(ctx, nu.BinaryStream(), nu.BufferSize(streamChunkSize))
+	if err != nil {
+		return fmt.Errorf("creating result stream: %w", err)
+	}
+	defer out.Close()
+	return writeChunks(ctx, out, v)
+}
+
+// writeChunks writes v to w in chunks of at most streamChunkSize bytes.
+func writeChunks(ctx context.Context, w io.Writer, v []byte) error {
+	for len(v) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := min(len(v), streamChunkSize)
+		if _, err := w.Write(v[:n]); err != nil {
+			return fmt.Errorf("writing value: %w", err)
+		}
+		v = v[n:]
+	}
+	return nil
+}
+
+const streamChunkSize = 64 * 1024
+
+/*
+getValues returns table of {key, value, found} records for the given list of
+keys, all the keys are read in a single transaction.
+*/
+func getValues(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand, path []boltItem, keys []boltItem, valueFilter func([]byte) bool, format func([]byte) nu.Value, decode func([]byte) (nu.Value, error)) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		buckets, err := findBuckets(tx, path)
+		if err != nil {
+			return err
 		}
+
+		out, err := call.ReturnListStream(ctx)
+		if err != nil {
+			return fmt.Errorf("creating result stream: %w", err)
+		}
+		defer close(out)
+
+		keyValues(buckets, keys, valueFilter, format, decode, func(v nu.Value) { out <- v })
 		return nil
 	})
 }
 
+/*
+keyValues emits {key, value, found} record for every key in every bucket.
+When the key doesn't exist (or the value doesn't pass the filter) the value
+is null and found is false.
+*/
+func keyValues(buckets []bucketMatch, keys []boltItem, valueFilter func([]byte) bool, format func([]byte) nu.Value, decode func([]byte) (nu.Value, error), emit func(nu.Value)) {
+	for _, m := range buckets {
+		tag := m.tagger(format)
+		for _, key := range keys {
+			row := nu.Record{"key": format(key.name), "value": nu.Value{}, "found": nu.Value{Value: false}}
+			if v := m.bucket.Get(key.name); v != nil && valueFilter(v) {
+				setValueField(row, v, decode)
+				row["found"] = nu.Value{Value: true}
+			}
+			emit(tag(nu.Value{Value: row}, ""))
+		}
+	}
+}
+
+/*
+keyList returns list of keys given either by the "keys" flag or as input.
+Nil is returned when neither is given.
+*/
+func keyList(call *nu.ExecCommand) ([]boltItem, error) {
+	var items []nu.Value
+	if v, ok := call.FlagValue("keys"); ok {
+		items = v.Value.([]nu.Value)
+	} else {
+		if call.Input == nil {
+			return nil, nil
+		}
+		var err error
+		if items, err = inputItems(call.Input); err != nil {
+			return nil, err
+		}
+	}
+	return encodeKeys(items, getKeyEncoder(call))
+}
+
+/*
+inputItems returns command input as list - single value input is returned as
+list of one item.
+*/
+func inputItems(input any) (items []nu.Value, _ error) {
+	switch in := input.(type) {
+	case nu.Value:
+		var ok bool
+		if items, ok = in.Value.([]nu.Value); !ok {
+			items = []nu.Value{in}
+		}
+	case <-chan nu.Value:
+		for v := range in {
+			items = append(items, v)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported input type %T", input)
+	}
+	return items, nil
+}
+
+func encodeKeys(items []nu.Value, encode func(nu.Value) ([]byte, error)) ([]boltItem, error) {
+	keys := make([]boltItem, 0, len(items))
+	for _, v := range items {
+		k, err := encode(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key name: %w", err)
+		}
+		keys = append(keys, boltItem{name: k, span: v.Span})
+	}
+	return keys, nil
+}
+
 func setValue(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
 	path, key, err := location(call)
 	if err != nil {
@@ -63,13 +229,84 @@ func setValue(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
 	if err != nil {
 		return err
 	}
-	return db.Update(func(tx *bbolt.Tx) error {
-		b, err := goToBucket(tx, path)
+	cond, err := getWriteCondition(call)
+	if err != nil {
+		return err
+	}
+	create := boolFlag(call, "create")
+	bucket := bucketFunc(create)
+	fill, err := getFillPercent(call)
+	if err != nil {
+		return err
+	}
+
+	var written bool
+	var previous []byte
+	sent, err := update(ctx, db, call, func(tx *writeTx) error {
+		b, err := bucket(tx, path)
 		if err != nil {
 			return err
 		}
-		return b.Put(key.name, v)
+		if fill != 0 {
+			b.FillPercent = fill
+		}
+		written, previous, err = putIf(tx, b, key.name, v, cond)
+		return err
 	})
+	if sent || err != nil || cond == nil {
+		return err
+	}
+	// nothing (rather than empty binary) when the key didn't exist
+	prev := nu.Value{}
+	if previous != nil {
+		prev.Value = previous
+	}
+	return call.ReturnValue(ctx, nu.Value{Value: nu.Record{
+		"written":  nu.Value{Value: written},
+		"previous": prev,
+	}})
+}
+
+/*
+putIf writes the value of the key when the condition accepts the current
+value of the key (nil condition means unconditional write). The returned
+"previous" is the value of the key before the write, nil when the key didn't
+exist (existing empty value is returned as non-nil empty slice).
+*/
+func putIf(tx *writeTx, b *bbolt.Bucket, key, value []byte, cond func(current []byte) bool) (written bool, previous []byte, err error) {
+	if cond != nil {
+		previous = slices.Clone(b.Get(key))
+		if !cond(previous) {
+			return false, previous, nil
+		}
+	}
+	return true, previous, tx.put(b, key, value)
+}
+
+/*
+getWriteCondition returns the condition based on the "if-absent" and
+"if-equals" flags, the condition is called with the current value of the
+key (nil when the key doesn't exist) and it returns true when the write
+should happen. Nil is returned when the write is unconditional.
+*/
+func getWriteCondition(call *nu.ExecCommand) (func(current []byte) bool, error) {
+	if boolFlag(call, "if-absent") {
+		return isAbsent, nil
+	}
+	if v, ok := call.FlagValue("if-equals"); ok {
+		expected, err := toBytes(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expected value: %w", err)
+		}
+		return isEqualTo(expected), nil
+	}
+	return nil, nil
+}
+
+func isAbsent(current []byte) bool { return current == nil }
+
+func isEqualTo(expected []byte) func(current []byte) bool {
+	return func(current []byte) bool { return current != nil && bytes.Equal(current, expected) }
 }
 
 func inputValue(call *nu.ExecCommand) ([]byte, error) {
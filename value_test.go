@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+func Test_putIf(t *testing.T) {
+	db := testDB(t, "a", "b")
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("test"))
+		if err := b.Put([]byte("empty"), []byte{}); err != nil {
+			return err
+		}
+
+		var testCases = []struct {
+			name     string
+			key      string
+			cond     func([]byte) bool
+			written  bool
+			previous []byte
+		}{
+			{name: "unconditional", key: "b", cond: nil, written: true, previous: nil},
+			{name: "absent, key doesn't exist", key: "new", cond: isAbsent, written: true, previous: nil},
+			{name: "absent, key exists", key: "a", cond: isAbsent, written: false, previous: []byte("value of a")},
+			{name: "absent, empty value exists", key: "empty", cond: isAbsent, written: false, previous: []byte{}},
+			{name: "equals, different value", key: "a", cond: isEqualTo([]byte("foo")), written: false, previous: []byte("value of a")},
+			{name: "equals, same value", key: "a", cond: isEqualTo([]byte("value of a")), written: true, previous: []byte("value of a")},
+			{name: "equals empty, key doesn't exist", key: "missing", cond: isEqualTo([]byte{}), written: false, previous: nil},
+			{name: "equals empty, empty value exists", key: "empty", cond: isEqualTo([]byte{}), written: true, previous: []byte{}},
+		}
+		for _, tc := range testCases {
+			before := bytes.Clone(b.Get([]byte(tc.key)))
+			written, previous, err := putIf(&writeTx{Tx: tx}, b, []byte(tc.key), []byte("updated"), tc.cond)
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+				continue
+			}
+			if written != tc.written {
+				t.Errorf("%s: expected written %t, got %t", tc.name, tc.written, written)
+			}
+			// the key didn't exist (nil) must be distinguishable from empty value
+			if (previous == nil) != (tc.previous == nil) || !bytes.Equal(previous, tc.previous) {
+				t.Errorf("%s: expected previous %#v, got %#v", tc.name, tc.previous, previous)
+			}
+			if v := b.Get([]byte(tc.key)); written && string(v) != "updated" {
+				t.Errorf("%s: expected value to be written, got %q", tc.name, v)
+			} else if !written && !bytes.Equal(v, before) {
+				t.Errorf("%s: expected value not to change, got %q", tc.name, v)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_keyValues(t *testing.T) {
+	db := testDB(t, "a", "b")
+
+	// get returns rows formatted as "key=value found"
+	get := func(t *testing.T, input any) []string {
+		t.Helper()
+		items, err := inputItems(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys, err := encodeKeys(items, toBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rows []string
+		err = db.View(func(tx *bbolt.Tx) error {
+			buckets, err := findBuckets(tx, []boltItem{{name: []byte("test")}})
+			if err != nil {
+				return err
+			}
+			format := func(b []byte) nu.Value { return nu.Value{Value: string(b)} }
+			keyValues(buckets, keys, func([]byte) bool { return true }, format, nil, func(v nu.Value) {
+				r := v.Value.(nu.Record)
+				value := "null"
+				if b, ok := r["value"].Value.([]byte); ok {
+					value = string(b)
+				}
+				rows = append(rows, fmt.Sprintf("%s=%s %t", r["key"].Value, value, r["found"].Value))
+			})
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	t.Run("list input", func(t *testing.T) {
+		rows := get(t, nu.Value{Value: []nu.Value{{Value: "b"}, {Value: "a"}}})
+		if expected := []string{"b=value of b true", "a=value of a true"}; !slices.Equal(rows, expected) {
+			t.Errorf("expected %q, got %q", expected, rows)
+		}
+	})
+
+	t.Run("single binary input", func(t *testing.T) {
+		rows := get(t, nu.Value{Value: []byte("a")})
+		if expected := []string{"a=value of a true"}; !slices.Equal(rows, expected) {
+			t.Errorf("expected %q, got %q", expected, rows)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		rows := get(t, nu.Value{Value: []nu.Value{{Value: "a"}, {Value: "x"}}})
+		if expected := []string{"a=value of a true", "x=null false"}; !slices.Equal(rows, expected) {
+			t.Errorf("expected %q, got %q", expected, rows)
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		items, err := inputItems(nu.Value{Value: []nu.Value{{Value: "a"}, {Value: nu.Record{"k": nu.Value{Value: "a"}}}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys, err := encodeKeys(items, toBytes); err == nil || !strings.Contains(err.Error(), "invalid key name") {
+			t.Errorf("expected invalid key error, got %d keys and error %v", len(keys), err)
+		}
+	})
+}
+
+// chunkWriter records the size of every Write call
+type chunkWriter struct {
+	bytes.Buffer
+	chunks []int
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.chunks = append(w.chunks, len(p))
+	return w.Buffer.Write(p)
+}
+
+func Test_writeChunks(t *testing.T) {
+	value := make([]byte, 2*streamChunkSize+100)
+	for i := range value {
+		value[i] = byte(i % 251)
+	}
+
+	w := &chunkWriter{}
+	if err := writeChunks(context.Background(), w, value); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int{streamChunkSize, streamChunkSize, 100}; !slices.Equal(w.chunks, expected) {
+		t.Errorf("expected chunks %v, got %v", expected, w.chunks)
+	}
+	if !bytes.Equal(w.Bytes(), value) {
+		t.Error("written bytes differ from the value")
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w := &chunkWriter{}
+		if err := writeChunks(ctx, w, value); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if len(w.chunks) != 0 {
+			t.Errorf("expected nothing to be written, got %v", w.chunks)
+		}
+	})
+}
+
+func Test_setCreate(t *testing.T) {
+	db := testDB(t, "a")
+	item := func(name string) boltItem { return boltItem{name: []byte(name)} }
+	wildcard := boltItem{match: func([]byte) bool { return true }}
+
+	// set runs putIf in the bucket returned by bucketFunc
+	set := func(create bool, path []boltItem) error {
+		return db.Update(func(tx *bbolt.Tx) error {
+			wtx := &writeTx{Tx: tx}
+			b, err := bucketFunc(create)(wtx, path)
+			if err != nil {
+				return err
+			}
+			_, _, err = putIf(wtx, b, []byte("k"), []byte("v"), nil)
+			return err
+		})
+	}
+	path := []boltItem{item("test"), item("new"), item("nested")}
+
+	if err := set(false, path); err == nil {
+		t.Error("expected error without create when the bucket doesn't exist")
+	}
+
+	if err := set(true, path); err != nil {
+		t.Fatalf("set with create: %v", err)
+	}
+	err := db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("test")).Bucket([]byte("new")).Bucket([]byte("nested"))
+		if b == nil {
+			t.Fatal("expected nested buckets to be created")
+		}
+		if v := b.Get([]byte("k")); string(v) != "v" {
+			t.Errorf("expected value %q, got %q", "v", v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// wildcard is rejected and the buckets created before it are rolled back
+	err = set(true, []boltItem{item("test"), item("other"), wildcard, item("x")})
+	if err == nil || !strings.Contains(err.Error(), "wildcards in the bucket path are not supported") {
+		t.Errorf("expected wildcard error, got %v", err)
+	}
+	err = db.View(func(tx *bbolt.Tx) error {
+		if tx.Bucket([]byte("test")).Bucket([]byte("other")) != nil {
+			t.Error("expected bucket \"other\" not to be created")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+/*
+walk streams all the items (nested buckets and keys) under the bucket,
+recursively.
+*/
+func walk(ctx context.Context, db *bbolt.DB, call *nu.ExecCommand) error {
+	path, _, err := location(call)
+	if err != nil {
+		return err
+	}
+
+	maxDepth := int64(-1)
+	if v, ok := call.FlagValue("max-depth"); ok {
+		if maxDepth = v.Value.(int64); maxDepth < 1 {
+			return nu.Error{
+				Err:    errors.New("max depth must be at least 1"),
+				Labels: []nu.Label{{Text: "invalid max depth", Span: v.Span}},
+			}
+		}
+	}
+	values := boolFlag(call, "values")
+
+	format := getFormatter(call)
+
+	return db.View(func(tx *bbolt.Tx) error {
+		b, err := goToBucket(tx, path)
+		if err != nil {
+			return err
+		}
+
+		out, err := call.ReturnListStream(ctx)
+		if err != nil {
+			return fmt.Errorf("creating result stream: %w", err)
+		}
+		defer close(out)
+
+		w := walker{maxDepth: maxDepth, values: values, format: format, emit: func(v nu.Value) { out <- v }}
+		return w.walk(ctx, b, nil)
+	})
+}
+
+/*
+walker emits the items of the bucket and (up to maxDepth, negative means no
+limit) it's nested buckets as {path, depth, kind, size, value?} records.
+*/
+type walker struct {
+	maxDepth int64
+	values   bool
+	format   func([]byte) nu.Value
+	emit     func(nu.Value)
+}
+
+func (w walker) walk(ctx context.Context, b *bbolt.Bucket, path []nu.Value) error {
+	depth := int64(len(path) + 1)
+	c := b.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		item := nu.Record{
+			"path":  nu.Value{Value: append(slices.Clip(path), w.format(k))},
+			"depth": nu.Value{Value: depth},
+		}
+		if v == nil {
+			item["kind"] = nu.Value{Value: "bucket"}
+			item["size"] = nu.Value{Value: nil}
+		} else {
+			item["kind"] = nu.Value{Value: "key"}
+			item["size"] = nu.Value{Value: nu.Filesize(len(v))}
+		}
+		if w.values {
+			item["value"] = nu.Value{Value: slices.Clone(v)}
+		}
+		w.emit(nu.Value{Value: item})
+
+		if v == nil && (w.maxDepth < 0 || depth < w.maxDepth) {
+			if err := w.walk(ctx, b.Bucket(k), item["path"].Value.([]nu.Value)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/ainvaltin/nu-plugin"
+)
+
+func Test_walker(t *testing.T) {
+	db := testDB(t, "a", "b")
+	err := db.Update(func(tx *bbolt.Tx) error {
+		sub, err := tx.Bucket([]byte("test")).CreateBucket([]byte("sub"))
+		if err != nil {
+			return err
+		}
+		if err := sub.Put([]byte("x"), []byte("xx")); err != nil {
+			return err
+		}
+		deep, err := sub.CreateBucket([]byte("deep"))
+		if err != nil {
+			return err
+		}
+		return deep.Put([]byte("y"), []byte("yyy"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// walk returns rows formatted as "path depth kind size"
+	walk := func(t *testing.T, w walker) (rows []string, values [][]byte) {
+		t.Helper()
+		if w.format == nil {
+			w.format = func(b []byte) nu.Value { return nu.Value{Value: string(b)} }
+		}
+		w.emit = func(v nu.Value) {
+			r := v.Value.(nu.Record)
+			var path []string
+			for _, p := range r["path"].Value.([]nu.Value) {
+				path = append(path, p.Value.(string))
+			}
+			size := "-"
+			if s, ok := r["size"].Value.(nu.Filesize); ok {
+				size = fmt.Sprint(int64(s))
+			}
+			rows = append(rows, strings.Join([]string{strings.Join(path, "/"), fmt.Sprint(r["depth"].Value), r["kind"].Value.(string), size}, " "))
+			if v, ok := r["value"]; ok {
+				b, _ := v.Value.([]byte)
+				values = append(values, b)
+			}
+		}
+		err := db.View(func(tx *bbolt.Tx) error {
+			return w.walk(context.Background(), tx.Bucket([]byte("test")), nil)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows, values
+	}
+
+	t.Run("all", func(t *testing.T) {
+		rows, values := walk(t, walker{maxDepth: -1})
+		expected := []string{"a 1 key 10", "b 1 key 10", "sub 1 bucket -", "sub/deep 2 bucket -", "sub/deep/y 3 key 3", "sub/x 2 key 2"}
+		if !slices.Equal(rows, expected) {
+			t.Errorf("expected\n%q\ngot\n%q", expected, rows)
+		}
+		if values != nil {
+			t.Errorf("expected no values, got %q", values)
+		}
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		rows, _ := walk(t, walker{maxDepth: 1})
+		if expected := []string{"a 1 key 10", "b 1 key 10", "sub 1 bucket -"}; !slices.Equal(rows, expected) {
+			t.Errorf("expected\n%q\ngot\n%q", expected, rows)
+		}
+		rows, _ = walk(t, walker{maxDepth: 2})
+		if expected := []string{"a 1 key 10", "b 1 key 10", "sub 1 bucket -", "sub/deep 2 bucket -", "sub/x 2 key 2"}; !slices.Equal(rows, expected) {
+			t.Errorf("expected\n%q\ngot\n%q", expected, rows)
+		}
+	})
+
+	t.Run("values", func(t *testing.T) {
+		_, values := walk(t, walker{maxDepth: 1, values: true})
+		// buckets have no value
+		if expected := [][]byte{[]byte("value of a"), []byte("value of b"), nil}; !slices.EqualFunc(values, expected, func(a, b []byte) bool { return string(a) == string(b) && (a == nil) == (b == nil) }) {
+			t.Errorf("expected %q, got %q", expected, values)
+		}
+	})
+
+	t.Run("path format", func(t *testing.T) {
+		hex := func(b []byte) nu.Value { return nu.Value{Value: fmt.Sprintf("%x", b)} }
+		rows, _ := walk(t, walker{maxDepth: -1, format: hex})
+		if expected := "737562/64656570/79 3 key 3"; rows[4] != expected {
+			t.Errorf("expected row %q, got %q", expected, rows[4])
+		}
+	})
+}